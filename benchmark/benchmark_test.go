@@ -19,7 +19,7 @@ func TestBenchmark(t *testing.T) {
 		result interface{}
 	}{
 		{`fun fib(n) { if(n < 2) return n; return fib(n - 1) + fib(n - 2);}
-				 print fib(35);`, 9227465.0},
+				 print fib(35);`, int64(9227465)},
 	}
 
 	for _, test := range tests {
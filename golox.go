@@ -2,21 +2,19 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"golox/loxerror"
 	"golox/vm"
 )
 
 func runFile(path string) {
-	code, err := ioutil.ReadFile(path)
-	if err != nil {
-		fmt.Print(err)
-		return
+	if newVM().InterpretFile(path) == vm.INTERPRET_RUNTIME_ERROR {
+		os.Exit(70)
 	}
-	run(string(code))
 }
 
 func runPrompt() {
@@ -30,17 +28,34 @@ func runPrompt() {
 	}
 }
 
-func run(source string) {
+func run(source string) vm.InterpretResult {
+	return newVM().Interpret(source)
+}
+
+var stripAsserts = flag.Bool("strip-asserts", false, "compile scripts without their assert statements")
+
+// newVM creates a VM that also looks for modules in the directories listed
+// in LOX_PATH.
+func newVM() *vm.VM {
 	vmachine := vm.New()
-	vmachine.Interpret(source)
+	vmachine.StripAsserts = *stripAsserts
+	if loxPath := os.Getenv("LOX_PATH"); loxPath != "" {
+		vmachine.SearchPath = filepath.SplitList(loxPath)
+	}
+	return vmachine
 }
 
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: golox [script]")
+	flag.Usage = func() {
+		fmt.Println("Usage: golox [-strip-asserts] [script]")
+	}
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		flag.Usage()
 		os.Exit(64)
-	} else if len(os.Args) == 2 {
-		runFile(os.Args[1])
+	} else if flag.NArg() == 1 {
+		runFile(flag.Arg(0))
 	} else {
 		runPrompt()
 	}
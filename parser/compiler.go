@@ -10,16 +10,34 @@ type Compiler struct {
 	Function   *repr.Function
 	Type       repr.FuncType
 	Locals     []Local
+	Upvalues   []Upvalue
 	ScopeDepth int
+	Loop       *Loop
+	Try        *TryBlock
+}
+
+type ClassCompiler struct {
+	Enclosing     *ClassCompiler
+	HasSuperclass bool
 }
 
 func InitCompiler(funcType repr.FuncType, name string) *Compiler {
+	// Slot zero holds the receiver inside methods and the called function
+	// everywhere else.
+	receiver := token.Token{}
+	if funcType == repr.FUNC_METHOD || funcType == repr.FUNC_INITIALIZER {
+		receiver = token.Token{Type: token.THIS, Lexeme: "this"}
+	}
+
 	return &Compiler{
 		nil,
 		&repr.Function{Chunk: repr.NewChunk(), Name: name},
 		funcType,
-		[]Local{{token.Token{}, 0}},
+		[]Local{{receiver, 0, false, false, false}},
+		[]Upvalue{},
 		0,
+		nil,
+		nil,
 	}
 }
 
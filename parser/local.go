@@ -1,15 +1,42 @@
 package parser
 
 import (
+	"golox/loxerror"
 	"golox/token"
 )
 
 type Local struct {
-	Name  token.Token
-	Depth int
+	Name       token.Token
+	Depth      int
+	IsCaptured bool
+	// Hidden locals cannot be referred to by name.
+	Hidden  bool
+	IsConst bool
+}
+
+type Upvalue struct {
+	Index   byte
+	IsLocal bool
 }
 
 func (p *Parser) addLocal(name token.Token) {
-	local := Local{name, -1}
+	local := Local{name, -1, false, false, false}
 	p.Compiler.Locals = append(p.Compiler.Locals, local)
 }
+
+func (p *Parser) addUpvalue(compiler *Compiler, index byte, isLocal bool) int {
+	for i, upvalue := range compiler.Upvalues {
+		if upvalue.Index == index && upvalue.IsLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(compiler.Upvalues) == 256 {
+		loxerror.Error(p.PrevToken().Line, "Too many closure variables in function.")
+		return 0
+	}
+
+	compiler.Upvalues = append(compiler.Upvalues, Upvalue{index, isLocal})
+	compiler.Function.UpvalueCount++
+	return len(compiler.Upvalues) - 1
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"golox/repr"
+)
+
+type Loop struct {
+	Enclosing  *Loop
+	Start      int
+	ScopeDepth int
+	BreakJumps []int
+}
+
+// beginLoop starts tracking a loop whose body is about to be compiled.
+// Continuing jumps back to start; breaking jumps past the end of the loop.
+func (p *Parser) beginLoop(start int) {
+	p.Compiler.Loop = &Loop{p.Compiler.Loop, start, p.Compiler.ScopeDepth, []int{}}
+}
+
+// endLoop patches every break in the current loop to jump to the current
+// position.
+func (p *Parser) endLoop() {
+	for _, breakJump := range p.Compiler.Loop.BreakJumps {
+		p.patchJump(breakJump)
+	}
+	p.Compiler.Loop = p.Compiler.Loop.Enclosing
+}
+
+// discardLocals emits code popping every local declared deeper than depth,
+// without forgetting them at compile time. The scopes they belong to are
+// still being compiled after a break or continue.
+func (p *Parser) discardLocals(depth int) {
+	for i := len(p.Compiler.Locals) - 1; i >= 0 && p.Compiler.Locals[i].Depth > depth; i-- {
+		if p.Compiler.Locals[i].IsCaptured {
+			p.emitByte(repr.OP_CLOSE_UPVALUE)
+		} else {
+			p.emitByte(repr.OP_POP)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"golox/loxerror"
+	"golox/repr"
+	"golox/token"
+)
+
+// matchStatement compiles 'match (subject) { case pattern => statement ... }'
+// into a chain of tests. The subject is kept in a hidden local, and every
+// case that does not match jumps to the next one. A pattern is one of:
+//
+//   - literals separated by '|', matching values equal to any of them;
+//   - '_', matching anything;
+//   - a name, matching anything and binding the subject to it.
+//
+// Any of them can be followed by 'if guard', which must also be truthy for
+// the case to match. Nothing happens when no case matches.
+func (p *Parser) matchStatement() {
+	p.beginScope()
+	p.consume(token.LEFT_PAREN, "Expect '(' after 'match'.")
+	p.expression()
+	p.consume(token.RIGHT_PAREN, "Expect ')' after match subject.")
+	p.consume(token.LEFT_BRACE, "Expect '{' before match cases.")
+
+	p.addLocal(p.syntheticToken(""))
+	p.markInitialized()
+	subject := byte(len(p.Compiler.Locals) - 1)
+
+	endJumps := []int{}
+	catchAll := false
+	for p.match(token.CASE) {
+		if catchAll {
+			loxerror.Error(p.PrevToken().Line, "Unreachable case after a catch-all pattern.")
+		}
+
+		var endJump int
+		endJump, catchAll = p.matchCase(subject)
+		endJumps = append(endJumps, endJump)
+	}
+	p.consume(token.RIGHT_BRACE, "Expect '}' after match cases.")
+
+	for _, endJump := range endJumps {
+		p.patchJump(endJump)
+	}
+	p.endScope()
+}
+
+// matchCase compiles a case of a match statement, returning the jump out of
+// the statement taken after running its body, and whether it matches every
+// value.
+func (p *Parser) matchCase(subject byte) (endJump int, catchAll bool) {
+	p.beginScope()
+	failJumps := []int{}
+	bound := false
+
+	if p.match(token.IDENTIFIER) {
+		if p.PrevToken().Lexeme != "_" {
+			p.emitBytes(repr.OP_GET_LOCAL, subject)
+			p.addLocal(p.PrevToken())
+			p.markInitialized()
+			bound = true
+		}
+		catchAll = true
+	} else {
+		p.literalPattern(subject)
+		for p.match(token.PIPE) {
+			elseJump := p.emitJump(repr.OP_JUMP_IF_FALSE)
+			matchJump := p.emitJump(repr.OP_JUMP)
+			p.patchJump(elseJump)
+			p.emitByte(repr.OP_POP)
+			p.literalPattern(subject)
+			p.patchJump(matchJump)
+		}
+		failJumps = append(failJumps, p.emitJump(repr.OP_JUMP_IF_FALSE))
+		p.emitByte(repr.OP_POP)
+	}
+
+	if p.match(token.IF) {
+		enclosing := p.guardEnd
+		p.guardEnd = p.findGuardEnd()
+		p.expression()
+		p.guardEnd = enclosing
+		failJumps = append(failJumps, p.emitJump(repr.OP_JUMP_IF_FALSE))
+		p.emitByte(repr.OP_POP)
+		catchAll = false
+	}
+
+	p.consume(token.ARROW, "Expect '=>' after pattern.")
+	p.statement()
+
+	captured := bound && p.Compiler.Locals[len(p.Compiler.Locals)-1].IsCaptured
+	p.endScope()
+	endJump = p.emitJump(repr.OP_JUMP)
+
+	// A failed test leaves its result on the stack, above the binding.
+	if len(failJumps) > 0 {
+		for _, failJump := range failJumps {
+			p.patchJump(failJump)
+		}
+		p.emitByte(repr.OP_POP)
+		if captured {
+			p.emitByte(repr.OP_CLOSE_UPVALUE)
+		} else if bound {
+			p.emitByte(repr.OP_POP)
+		}
+	}
+	return endJump, catchAll
+}
+
+// literalPattern compiles the comparison of the subject with a literal.
+func (p *Parser) literalPattern(subject byte) {
+	p.emitBytes(repr.OP_GET_LOCAL, subject)
+
+	negate := p.match(token.MINUS)
+	switch {
+	case p.match(token.NUMBER):
+		p.number(false)
+		if negate {
+			p.emitByte(repr.OP_NEGATE)
+		}
+	case !negate && p.match(token.STRING):
+		p.string(false)
+	case !negate && (p.match(token.TRUE) || p.match(token.FALSE) || p.match(token.NIL)):
+		p.literal(false)
+	default:
+		loxerror.Error(p.CurrToken().Line, "Expect literal, '_' or name as pattern.")
+	}
+	p.emitByte(repr.OP_EQUAL)
+}
+
+// findGuardEnd returns the index of the '=>' ending the guard about to be
+// compiled: the first one outside any brackets. A guard such as '(y)' would
+// otherwise be taken for the parameters of an arrow function.
+func (p *Parser) findGuardEnd() int {
+	depth := 0
+	for i := p.Current; i < len(p.Scanner.Tokens); i++ {
+		switch p.Scanner.Tokens[i].Type {
+		case token.LEFT_PAREN, token.LEFT_BRACKET, token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_BRACE:
+			depth--
+		case token.ARROW:
+			if depth == 0 {
+				return i
+			}
+		case token.EOF:
+			return -1
+		}
+	}
+	return -1
+}
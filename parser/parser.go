@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"golox/loxerror"
 	"golox/repr"
 	"golox/scanner"
@@ -8,16 +9,29 @@ import (
 )
 
 type Parser struct {
-	Current  int
-	Scanner  *scanner.Scanner
-	Compiler *Compiler
+	Current       int
+	Scanner       *scanner.Scanner
+	Compiler      *Compiler
+	ClassCompiler *ClassCompiler
+	// Constants holds the names of the global constants declared so far.
+	Constants map[string]bool
+	// Spawning is set while compiling the call of a spawn statement.
+	Spawning bool
+	// StripAsserts makes the parser check assert statements but emit no
+	// code for them.
+	StripAsserts bool
+	// guardEnd is the index of the '=>' ending the match guard being
+	// compiled, or -1.
+	guardEnd int
+	// lastCall is the offset of the last call instruction emitted.
+	lastCall int
 }
 
 func New(source string) *Parser {
 	sc := scanner.New(source)
 	comp := InitCompiler(repr.FUNC_SCRIPT, "")
 
-	return &Parser{0, sc, comp}
+	return &Parser{0, sc, comp, nil, make(map[string]bool), false, false, -1, -1}
 }
 
 func (p *Parser) Compile() *repr.Function {
@@ -67,7 +81,7 @@ func (p *Parser) match(tokenType token.Type) bool {
 }
 
 func (p *Parser) emitByte(byte byte) {
-	p.CurrChunk().Write(byte)
+	p.CurrChunk().Write(byte, p.PrevToken().Line)
 }
 
 func (p *Parser) emitBytes(byte1, byte2 byte) {
@@ -100,7 +114,12 @@ func (p *Parser) emitLoop(loopStart int) {
 }
 
 func (p *Parser) emitReturn() {
-	p.emitByte(repr.OP_NIL)
+	// Scripts return slot zero, which holds the module when one is imported.
+	if p.Compiler.Type == repr.FUNC_INITIALIZER || p.Compiler.Type == repr.FUNC_SCRIPT {
+		p.emitBytes(repr.OP_GET_LOCAL, 0)
+	} else {
+		p.emitByte(repr.OP_NIL)
+	}
 	p.emitByte(repr.OP_RETURN)
 }
 
@@ -156,13 +175,14 @@ func (p *Parser) getRule(tokenType token.Type) *ParseRule {
 	return rules[tokenType]
 }
 
-func (p *Parser) declareVariable() {
+func (p *Parser) declareVariable(name token.Token) {
 	if p.Compiler.ScopeDepth == 0 {
+		if p.Constants[name.Lexeme] {
+			loxerror.Error(name.Line, "Cannot redeclare constant '"+name.Lexeme+"'.")
+		}
 		return
 	}
 
-	name := p.PrevToken()
-
 	for i := len(p.Compiler.Locals) - 1; i >= 0; i-- {
 		local := p.Compiler.Locals[i]
 		if local.Depth != -1 && local.Depth < p.Compiler.ScopeDepth {
@@ -186,31 +206,81 @@ func (p *Parser) defineVariable(global byte) {
 	p.emitBytes(repr.OP_DEFINE_GLOBAL, global)
 }
 
+// compoundOps maps each compound assignment operator to the instruction that
+// combines the variable's current value with the right-hand side.
+var compoundOps = map[token.Type]byte{
+	token.PLUS_EQUAL:    repr.OP_ADD,
+	token.MINUS_EQUAL:   repr.OP_SUBTRACT,
+	token.STAR_EQUAL:    repr.OP_MULTIPLY,
+	token.SLASH_EQUAL:   repr.OP_DIVIDE,
+	token.PERCENT_EQUAL: repr.OP_MODULO,
+}
+
 func (p *Parser) namedVariable(name token.Token, canAssign bool) {
-	var arg, getOp, setOp byte
-	res := p.resolveLocal(name)
-	if res != -1 {
-		arg = byte(res)
-		getOp = repr.OP_GET_LOCAL
-		setOp = repr.OP_SET_LOCAL
-	} else {
-		arg = p.identifierConstant(name)
-		getOp = repr.OP_GET_GLOBAL
-		setOp = repr.OP_SET_GLOBAL
-	}
+	arg, getOp, setOp := p.resolveVariable(name)
 
 	if canAssign && p.match(token.EQUAL) {
+		p.checkAssignable(name)
+		p.expression()
+		p.emitBytes(setOp, arg)
+	} else if op, ok := compoundOps[p.CurrToken().Type]; canAssign && ok {
+		p.checkAssignable(name)
+		p.advance()
+		p.emitBytes(getOp, arg)
 		p.expression()
+		p.emitByte(op)
 		p.emitBytes(setOp, arg)
 	} else {
 		p.emitBytes(getOp, arg)
 	}
 }
 
+// resolveVariable returns the operand and the get/set instructions used to
+// access the variable called name from the current function.
+func (p *Parser) resolveVariable(name token.Token) (arg, getOp, setOp byte) {
+	if res := p.resolveLocal(p.Compiler, name); res != -1 {
+		return byte(res), repr.OP_GET_LOCAL, repr.OP_SET_LOCAL
+	} else if res := p.resolveUpvalue(p.Compiler, name); res != -1 {
+		return byte(res), repr.OP_GET_UPVALUE, repr.OP_SET_UPVALUE
+	}
+	return p.identifierConstant(name), repr.OP_GET_GLOBAL, repr.OP_SET_GLOBAL
+}
+
+// checkAssignable reports an error if name refers to a constant. Global
+// constants declared by other chunks are only caught at runtime.
+func (p *Parser) checkAssignable(name token.Token) {
+	for compiler := p.Compiler; compiler != nil; compiler = compiler.Enclosing {
+		for i := len(compiler.Locals) - 1; i >= 0; i-- {
+			local := compiler.Locals[i]
+			if !local.Hidden && p.identifiersEqual(name, local.Name) {
+				if local.IsConst {
+					loxerror.Error(name.Line, "Cannot assign to constant '"+name.Lexeme+"'.")
+				}
+				return
+			}
+		}
+	}
+
+	if p.Constants[name.Lexeme] {
+		loxerror.Error(name.Line, "Cannot assign to constant '"+name.Lexeme+"'.")
+	}
+}
+
+// emitIncrement adds or subtracts one from the value on top of the stack,
+// depending on whether operatorType is '++' or '--'.
+func (p *Parser) emitIncrement(operatorType token.Type) {
+	p.emitConstant(repr.IntVal(1))
+	if operatorType == token.PLUS_PLUS {
+		p.emitByte(repr.OP_ADD)
+	} else {
+		p.emitByte(repr.OP_SUBTRACT)
+	}
+}
+
 func (p *Parser) parseVariable(errorMessage string) byte {
 	p.consume(token.IDENTIFIER, errorMessage)
 
-	p.declareVariable()
+	p.declareVariable(p.PrevToken())
 	if p.Compiler.ScopeDepth > 0 {
 		return 0
 	}
@@ -222,6 +292,10 @@ func (p *Parser) identifierConstant(name token.Token) byte {
 	return p.CurrChunk().AddValue(repr.StringVal(name.Lexeme))
 }
 
+func (p *Parser) syntheticToken(text string) token.Token {
+	return token.Token{Type: token.IDENTIFIER, Lexeme: text, Line: p.PrevToken().Line}
+}
+
 func (p *Parser) identifiersEqual(a, b token.Token) bool {
 	return a.Lexeme == b.Lexeme
 }
@@ -233,10 +307,10 @@ func (p *Parser) markInitialized() {
 	p.Compiler.Locals[len(p.Compiler.Locals)-1].Depth = p.Compiler.ScopeDepth
 }
 
-func (p *Parser) resolveLocal(name token.Token) int {
-	for i := len(p.Compiler.Locals) - 1; i >= 0; i-- {
-		local := p.Compiler.Locals[i]
-		if p.identifiersEqual(name, local.Name) {
+func (p *Parser) resolveLocal(compiler *Compiler, name token.Token) int {
+	for i := len(compiler.Locals) - 1; i >= 0; i-- {
+		local := compiler.Locals[i]
+		if !local.Hidden && p.identifiersEqual(name, local.Name) {
 			if local.Depth == -1 {
 				loxerror.Error(-1, "Cannot read local variable in its own initializer.")
 			}
@@ -247,6 +321,26 @@ func (p *Parser) resolveLocal(name token.Token) int {
 	return -1
 }
 
+// resolveUpvalue looks for name in the enclosing functions, threading an
+// upvalue through every compiler between the one declaring the variable and
+// the one using it.
+func (p *Parser) resolveUpvalue(compiler *Compiler, name token.Token) int {
+	if compiler.Enclosing == nil {
+		return -1
+	}
+
+	if local := p.resolveLocal(compiler.Enclosing, name); local != -1 {
+		compiler.Enclosing.Locals[local].IsCaptured = true
+		return p.addUpvalue(compiler, byte(local), true)
+	}
+
+	if upvalue := p.resolveUpvalue(compiler.Enclosing, name); upvalue != -1 {
+		return p.addUpvalue(compiler, byte(upvalue), false)
+	}
+
+	return -1
+}
+
 func (p *Parser) beginScope() {
 	p.Compiler.ScopeDepth++
 }
@@ -255,11 +349,25 @@ func (p *Parser) endScope() {
 	p.Compiler.ScopeDepth--
 
 	for len(p.Compiler.Locals) > 0 && p.Compiler.Locals[len(p.Compiler.Locals)-1].Depth > p.Compiler.ScopeDepth {
-		p.emitByte(repr.OP_POP)
+		if p.Compiler.Locals[len(p.Compiler.Locals)-1].IsCaptured {
+			p.emitByte(repr.OP_CLOSE_UPVALUE)
+		} else {
+			p.emitByte(repr.OP_POP)
+		}
 		p.Compiler.Locals = p.Compiler.Locals[:len(p.Compiler.Locals)-1]
 	}
 }
 
+// consumeWord consumes an identifier acting as a keyword in one place only,
+// such as the 'as' of an import.
+func (p *Parser) consumeWord(word string, errMsg string) {
+	if p.check(token.IDENTIFIER) && p.CurrToken().Lexeme == word {
+		p.advance()
+		return
+	}
+	loxerror.Error(p.CurrToken().Line, errMsg)
+}
+
 func (p *Parser) consume(tokenType token.Type, errMsg string) {
 	if p.CurrToken().Type == tokenType {
 		p.advance()
@@ -275,10 +383,32 @@ func (p *Parser) and(canAssign bool) {
 	p.patchJump(endJump)
 }
 
-func (p *Parser) argumentList() byte {
+// argumentList compiles the arguments of a call up to and including the
+// closing ')'. Besides the argument count it returns the names of the named
+// arguments, which always follow the positional ones, and the positions of
+// the arguments spread with '...'.
+func (p *Parser) argumentList() (byte, []string, []int) {
 	argCount := 0
+	var names []string
+	var spreads []int
 	if !p.check(token.RIGHT_PAREN) {
 		for ok := true; ok; ok = p.match(token.COMMA) {
+			if p.check(token.IDENTIFIER) && p.Scanner.Tokens[p.Current+1].Type == token.COLON {
+				name := p.CurrToken().Lexeme
+				for _, other := range names {
+					if other == name {
+						loxerror.Error(p.CurrToken().Line, "Duplicate named argument '"+name+"'.")
+					}
+				}
+				names = append(names, name)
+				p.advance()
+				p.advance()
+			} else if len(names) > 0 {
+				loxerror.Error(p.CurrToken().Line, "Positional argument cannot follow named arguments.")
+			} else if p.match(token.ELLIPSIS) {
+				spreads = append(spreads, argCount)
+			}
+
 			p.expression()
 
 			if argCount == 255 {
@@ -288,15 +418,97 @@ func (p *Parser) argumentList() byte {
 		}
 	}
 
+	if len(names) > 0 && len(spreads) > 0 {
+		loxerror.Error(p.CurrToken().Line, "Cannot combine spread and named arguments.")
+	}
+
 	p.consume(token.RIGHT_PAREN, "Expect ')' after arguments.")
-	return byte(argCount)
+	return byte(argCount), names, spreads
+}
+
+// hasExtendedArguments reports whether the argument list following the '('
+// just consumed contains a named or spread argument.
+func (p *Parser) hasExtendedArguments() bool {
+	tokens := p.Scanner.Tokens
+	depth := 0
+	argStart := true
+	for i := p.Current; tokens[i].Type != token.EOF; i++ {
+		if depth == 0 && argStart {
+			if tokens[i].Type == token.ELLIPSIS || (tokens[i].Type == token.IDENTIFIER && tokens[i+1].Type == token.COLON) {
+				return true
+			}
+		}
+		argStart = false
+
+		switch tokens[i].Type {
+		case token.LEFT_PAREN, token.LEFT_BRACKET, token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_BRACE:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case token.COMMA:
+			argStart = depth == 0
+		}
+	}
+	return false
+}
+
+// emitCall emits the call of the value beneath argCount arguments, as
+// described by argumentList.
+func (p *Parser) emitCall(argCount byte, names []string, spreads []int) {
+	p.lastCall = len(p.CurrChunk().Code)
+	if len(names) > 0 {
+		items := make([]repr.Value, len(names))
+		for i, name := range names {
+			items[i] = repr.StringVal(name)
+		}
+		p.emitBytes(repr.OP_CALL_NAMED, argCount)
+		p.emitByte(p.makeConstant(repr.ListVal(repr.NewList(items))))
+	} else if len(spreads) > 0 {
+		items := make([]repr.Value, len(spreads))
+		for i, position := range spreads {
+			items[i] = repr.IntVal(int64(position))
+		}
+		p.emitBytes(repr.OP_CALL_SPREAD, argCount)
+		p.emitByte(p.makeConstant(repr.ListVal(repr.NewList(items))))
+	} else {
+		p.emitBytes(repr.OP_CALL, argCount)
+	}
+}
+
+// anonymousName is the name given to functions created by function
+// expressions.
+const anonymousName = "anonymous"
+
+// arrowFunction compiles '(params) => body', where the body is either a block
+// or a single expression whose value is returned.
+func (p *Parser) arrowFunction() {
+	p.beginFunction(repr.FUNC_FUNCTION, anonymousName)
+	p.parameters()
+	p.consume(token.ARROW, "Expect '=>' after parameters.")
+
+	if p.match(token.LEFT_BRACE) {
+		p.block()
+	} else {
+		p.expression()
+		p.emitByte(repr.OP_RETURN)
+	}
+
+	p.endFunction()
 }
 
 func (p *Parser) binary(canAssign bool) {
 	operatorType := p.PrevToken().Type
 
 	rule := p.getRule(operatorType)
-	p.parsePrecedence(rule.Precedence + 1)
+	if operatorType == token.STAR_STAR {
+		// Exponentiation is right-associative.
+		p.parsePrecedence(rule.Precedence)
+	} else {
+		p.parsePrecedence(rule.Precedence + 1)
+	}
 
 	switch operatorType {
 	case token.BANG_EQUAL:
@@ -319,6 +531,22 @@ func (p *Parser) binary(canAssign bool) {
 		p.emitByte(repr.OP_MULTIPLY)
 	case token.SLASH:
 		p.emitByte(repr.OP_DIVIDE)
+	case token.TILDE_SLASH:
+		p.emitByte(repr.OP_FLOOR_DIVIDE)
+	case token.PERCENT:
+		p.emitByte(repr.OP_MODULO)
+	case token.STAR_STAR:
+		p.emitByte(repr.OP_POWER)
+	case token.AMPERSAND:
+		p.emitByte(repr.OP_BIT_AND)
+	case token.PIPE:
+		p.emitByte(repr.OP_BIT_OR)
+	case token.CARET:
+		p.emitByte(repr.OP_BIT_XOR)
+	case token.LESS_LESS:
+		p.emitByte(repr.OP_SHIFT_LEFT)
+	case token.GREATER_GREATER:
+		p.emitByte(repr.OP_SHIFT_RIGHT)
 	}
 }
 
@@ -329,21 +557,184 @@ func (p *Parser) block() {
 	p.consume(token.RIGHT_BRACE, "Expect '}' after block.")
 }
 
+func (p *Parser) breakStatement() {
+	if p.Compiler.Loop == nil {
+		loxerror.Error(p.PrevToken().Line, "Cannot use 'break' outside of a loop.")
+		return
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after 'break'.")
+
+	loop := p.Compiler.Loop
+	p.exitTryBlocks(loop)
+	p.discardLocals(loop.ScopeDepth)
+	loop.BreakJumps = append(loop.BreakJumps, p.emitJump(repr.OP_JUMP))
+}
+
 func (p *Parser) call(canAssign bool) {
-	argCount := p.argumentList()
-	p.emitBytes(repr.OP_CALL, argCount)
+	p.emitCall(p.argumentList())
+}
+
+func (p *Parser) classDeclaration() {
+	p.consume(token.IDENTIFIER, "Expect class name.")
+	className := p.PrevToken()
+	nameConstant := p.identifierConstant(className)
+	p.declareVariable(p.PrevToken())
+
+	p.emitBytes(repr.OP_CLASS, nameConstant)
+	p.defineVariable(nameConstant)
+
+	classCompiler := &ClassCompiler{p.ClassCompiler, false}
+	p.ClassCompiler = classCompiler
+
+	if p.match(token.LESS) {
+		p.consume(token.IDENTIFIER, "Expect superclass name.")
+		p.namedVariable(p.PrevToken(), false)
+
+		if p.identifiersEqual(className, p.PrevToken()) {
+			loxerror.Error(p.PrevToken().Line, "A class cannot inherit from itself.")
+		}
+
+		// The superclass lives in a local named "super" so that methods can
+		// capture it as an upvalue.
+		p.beginScope()
+		p.addLocal(p.syntheticToken("super"))
+		p.defineVariable(0)
+
+		p.namedVariable(className, false)
+		p.emitByte(repr.OP_INHERIT)
+		classCompiler.HasSuperclass = true
+	}
+
+	p.namedVariable(className, false)
+	p.consume(token.LEFT_BRACE, "Expect '{' before class body.")
+	for !p.check(token.RIGHT_BRACE) && !p.check(token.EOF) {
+		p.method()
+	}
+	p.consume(token.RIGHT_BRACE, "Expect '}' after class body.")
+	p.emitByte(repr.OP_POP)
+
+	if classCompiler.HasSuperclass {
+		p.endScope()
+	}
+
+	p.ClassCompiler = p.ClassCompiler.Enclosing
+}
+
+func (p *Parser) continueStatement() {
+	if p.Compiler.Loop == nil {
+		loxerror.Error(p.PrevToken().Line, "Cannot use 'continue' outside of a loop.")
+		return
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after 'continue'.")
+
+	p.exitTryBlocks(p.Compiler.Loop)
+	p.discardLocals(p.Compiler.Loop.ScopeDepth)
+	p.emitLoop(p.Compiler.Loop.Start)
+}
+
+// enumDeclaration compiles 'enum Name { A, B, C }'. The member names are
+// pushed as constants for OP_ENUM to number in order.
+func (p *Parser) enumDeclaration() {
+	p.consume(token.IDENTIFIER, "Expect enum name.")
+	nameConstant := p.identifierConstant(p.PrevToken())
+	p.declareVariable(p.PrevToken())
+
+	p.consume(token.LEFT_BRACE, "Expect '{' before enum body.")
+	seen := make(map[string]bool)
+	for !p.check(token.RIGHT_BRACE) && !p.check(token.EOF) {
+		p.consume(token.IDENTIFIER, "Expect enum member name.")
+		member := p.PrevToken()
+		if seen[member.Lexeme] {
+			loxerror.Error(member.Line, "Duplicate enum member '"+member.Lexeme+"'.")
+		}
+		if len(seen) == 255 {
+			loxerror.Error(member.Line, "Cannot have more than 255 members in an enum.")
+		}
+		seen[member.Lexeme] = true
+		p.emitConstant(repr.StringVal(member.Lexeme))
+
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+	p.consume(token.RIGHT_BRACE, "Expect '}' after enum body.")
+
+	p.emitBytes(repr.OP_ENUM, nameConstant)
+	p.emitByte(byte(len(seen)))
+	p.defineVariable(nameConstant)
 }
 
 func (p *Parser) declaration() {
-	if p.match(token.VAR) {
+	if p.match(token.CLASS) {
+		p.classDeclaration()
+	} else if p.match(token.ENUM) {
+		p.enumDeclaration()
+	} else if p.match(token.VAR) {
 		p.varDeclaration()
-	} else if p.match(token.FUN) {
+	} else if p.match(token.CONST) {
+		p.constDeclaration()
+	} else if p.match(token.IMPORT) {
+		p.importDeclaration()
+	} else if p.match(token.EXPORT) {
+		p.exportDeclaration()
+	} else if p.check(token.FUN) && p.Scanner.Tokens[p.Current+1].Type == token.IDENTIFIER {
+		// Without a name, 'fun' starts an expression statement instead.
+		p.advance()
 		p.funDeclaration()
 	} else {
 		p.statement()
 	}
 }
 
+func (p *Parser) dot(canAssign bool) {
+	p.consume(token.IDENTIFIER, "Expect property name after '.'.")
+	name := p.identifierConstant(p.PrevToken())
+
+	if canAssign && p.match(token.EQUAL) {
+		p.expression()
+		p.emitBytes(repr.OP_SET_PROPERTY, name)
+	} else if p.match(token.LEFT_PAREN) {
+		// Named and spread arguments are resolved against the method, which
+		// OP_INVOKE never materializes, so such calls look the method up first.
+		// So do spawned calls, which need a plain OP_CALL to rewrite.
+		if p.hasExtendedArguments() || p.Spawning {
+			p.emitBytes(repr.OP_GET_PROPERTY, name)
+			p.emitCall(p.argumentList())
+			return
+		}
+		argCount, _, _ := p.argumentList()
+		p.emitBytes(repr.OP_INVOKE, name)
+		p.emitByte(argCount)
+	} else {
+		p.emitBytes(repr.OP_GET_PROPERTY, name)
+	}
+}
+
+func (p *Parser) exportDeclaration() {
+	if p.Compiler.Type != repr.FUNC_SCRIPT || p.Compiler.ScopeDepth > 0 {
+		loxerror.Error(p.PrevToken().Line, "Can only export top-level declarations.")
+	}
+
+	name := p.Scanner.Tokens[p.Current+1]
+	if name.Type == token.IDENTIFIER {
+		p.Compiler.Function.Exports = append(p.Compiler.Function.Exports, name.Lexeme)
+	}
+
+	if p.match(token.CLASS) {
+		p.classDeclaration()
+	} else if p.match(token.ENUM) {
+		p.enumDeclaration()
+	} else if p.match(token.VAR) {
+		p.varDeclaration()
+	} else if p.match(token.CONST) {
+		p.constDeclaration()
+	} else if p.match(token.FUN) {
+		p.funDeclaration()
+	} else {
+		loxerror.Error(p.CurrToken().Line, "Expect declaration after 'export'.")
+	}
+}
+
 func (p *Parser) expression() {
 	p.parsePrecedence(PREC_ASSIGNMENT)
 }
@@ -358,6 +749,12 @@ func (p *Parser) forStatement() {
 	p.beginScope()
 
 	p.consume(token.LEFT_PAREN, "Expect '(' after 'for'.")
+	if p.isForIn() {
+		p.forInStatement()
+		p.endScope()
+		return
+	}
+
 	if p.match(token.SEMICOLON) {
 		// No initializer.
 	} else if p.match(token.VAR) {
@@ -390,6 +787,8 @@ func (p *Parser) forStatement() {
 		p.patchJump(bodyJump)
 	}
 
+	// Continuing runs the increment clause, if there is one.
+	p.beginLoop(loopStart)
 	p.statement()
 
 	p.emitLoop(loopStart)
@@ -398,37 +797,135 @@ func (p *Parser) forStatement() {
 		p.patchJump(exitJump)
 		p.emitByte(repr.OP_POP)
 	}
+	p.endLoop()
 
 	p.endScope()
 }
 
+// isForIn looks ahead for the 'var x in' starting a for-in loop.
+func (p *Parser) isForIn() bool {
+	tokens := p.Scanner.Tokens[p.Current:]
+	return len(tokens) > 2 && (tokens[0].Type == token.VAR || tokens[0].Type == token.CONST) &&
+		tokens[1].Type == token.IDENTIFIER && tokens[2].Type == token.IN
+}
+
+// forInStatement compiles the rest of 'for (var x in iterable)'. The iterator
+// is kept in a hidden local, and every iteration binds its value to a fresh
+// local so that closures capture the value of their own iteration.
+func (p *Parser) forInStatement() {
+	isConst := p.match(token.CONST)
+	if !isConst {
+		p.consume(token.VAR, "Expect 'var' or 'const' in for-in loop.")
+	}
+	p.consume(token.IDENTIFIER, "Expect variable name.")
+	name := p.PrevToken()
+	p.consume(token.IN, "Expect 'in' after loop variable.")
+	p.expression()
+	p.consume(token.RIGHT_PAREN, "Expect ')' after for clauses.")
+
+	p.emitByte(repr.OP_GET_ITER)
+	p.addLocal(p.syntheticToken(""))
+	p.Compiler.Locals[len(p.Compiler.Locals)-1].Depth = p.Compiler.ScopeDepth
+
+	loopStart := len(p.CurrChunk().Code)
+	exitJump := p.emitJump(repr.OP_FOR_ITER)
+
+	p.beginLoop(loopStart)
+	p.beginScope()
+	p.declareVariable(name)
+	p.markInitialized()
+	p.Compiler.Locals[len(p.Compiler.Locals)-1].IsConst = isConst
+	p.statement()
+	p.endScope()
+
+	p.emitLoop(loopStart)
+	p.patchJump(exitJump)
+	p.endLoop()
+}
+
 func (p *Parser) function(funcType repr.FuncType) {
-	funcCompiler := InitCompiler(funcType, p.PrevToken().Lexeme)
+	p.beginFunction(funcType, p.PrevToken().Lexeme)
+
+	p.consume(token.LEFT_PAREN, "Expect '(' after function name.")
+	p.parameters()
+
+	p.consume(token.LEFT_BRACE, "Expect '{' before function body.")
+	p.block()
+
+	p.endFunction()
+}
+
+func (p *Parser) beginFunction(funcType repr.FuncType, name string) {
+	funcCompiler := InitCompiler(funcType, name)
 	p.encloseCompiler(funcCompiler)
 	p.beginScope()
+}
 
-	p.consume(token.LEFT_PAREN, "Expect '(' after function name.")
+// endFunction finishes the function being compiled and emits the closure that
+// creates it at runtime in the enclosing function.
+func (p *Parser) endFunction() {
+	compiledFunction := p.endCompiler()
+	upvalues := p.Compiler.Upvalues
+
+	p.restoreCompiler()
+
+	p.emitBytes(repr.OP_CLOSURE, p.makeConstant(repr.FunctionVal(compiledFunction)))
+	for _, upvalue := range upvalues {
+		if upvalue.IsLocal {
+			p.emitByte(1)
+		} else {
+			p.emitByte(0)
+		}
+		p.emitByte(upvalue.Index)
+	}
+}
+
+// parameters compiles a parameter list up to and including the closing ')'.
+func (p *Parser) parameters() {
+	function := p.Compiler.Function
 	if !p.check(token.RIGHT_PAREN) {
 		for ok := true; ok; ok = p.match(token.COMMA) {
-			p.Compiler.Function.Arity++
-			if p.Compiler.Function.Arity > 255 {
+			function.Arity++
+			if function.Arity > 255 {
 				loxerror.Error(p.CurrToken().Line, "Cannot have more than 255 parameters.")
 			}
 
+			isRest := p.match(token.ELLIPSIS)
 			paramConstant := p.parseVariable("Expect parameter name.")
+			function.Params = append(function.Params, p.PrevToken().Lexeme)
 			p.defineVariable(paramConstant)
+
+			if isRest {
+				function.Variadic = true
+				if p.check(token.EQUAL) {
+					loxerror.Error(p.CurrToken().Line, "Rest parameter cannot have a default value.")
+				} else if !p.check(token.RIGHT_PAREN) {
+					loxerror.Error(p.CurrToken().Line, "Rest parameter must be the last parameter.")
+				}
+			} else if p.match(token.EQUAL) {
+				p.defaultValue(byte(function.Arity))
+			} else if function.Defaults > 0 {
+				loxerror.Error(p.PrevToken().Line, "Parameter without a default value cannot follow one with a default.")
+			}
 		}
 	}
 	p.consume(token.RIGHT_PAREN, "Expect ')' after parameters.")
+}
 
-	p.consume(token.LEFT_BRACE, "Expect '{' before function body.")
-	p.block()
+// defaultValue compiles the default of the parameter in slot. It is evaluated
+// at call time, and only when the caller omitted that argument.
+func (p *Parser) defaultValue(slot byte) {
+	p.Compiler.Function.Defaults++
 
-	compiledFunction := p.endCompiler()
+	p.emitBytes(repr.OP_JUMP_IF_PRESENT, slot)
+	p.emitBytes(0xff, 0xff)
+	skipJump := len(p.CurrChunk().Code) - 2
 
-	p.restoreCompiler()
+	p.expression()
+	p.emitBytes(repr.OP_SET_LOCAL, slot)
+	p.emitByte(repr.OP_POP)
 
-	p.emitBytes(repr.OP_CONSTANT, p.makeConstant(repr.FunctionVal(compiledFunction)))
+	p.patchJump(skipJump)
 }
 
 func (p *Parser) funDeclaration() {
@@ -438,11 +935,100 @@ func (p *Parser) funDeclaration() {
 	p.defineVariable(global)
 }
 
+func (p *Parser) funExpression(canAssign bool) {
+	p.beginFunction(repr.FUNC_FUNCTION, anonymousName)
+
+	p.consume(token.LEFT_PAREN, "Expect '(' after 'fun'.")
+	p.parameters()
+
+	p.consume(token.LEFT_BRACE, "Expect '{' before function body.")
+	p.block()
+
+	p.endFunction()
+}
+
 func (p *Parser) grouping(canAssign bool) {
+	if p.isArrowFunction() {
+		p.arrowFunction()
+		return
+	}
+
+	if canAssign && p.isDestructuring() {
+		p.destructuringAssignment()
+		return
+	}
+
 	p.expression()
+	if p.match(token.COMMA) {
+		itemCount := 1
+		for !p.check(token.RIGHT_PAREN) {
+			p.expression()
+			itemCount++
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+		p.consume(token.RIGHT_PAREN, "Expect ')' after tuple items.")
+		p.emitTuple(itemCount)
+		return
+	}
 	p.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
 }
 
+// emitTuple builds a tuple out of the itemCount values on top of the stack.
+func (p *Parser) emitTuple(itemCount int) {
+	if itemCount > 255 {
+		loxerror.Error(p.PrevToken().Line, "Cannot have more than 255 items in a tuple.")
+	}
+	p.emitBytes(repr.OP_BUILD_TUPLE, byte(itemCount))
+}
+
+// isDestructuring reports whether the parenthesis just consumed opens the
+// targets of a destructuring assignment, i.e. a list of at least two names
+// followed by ')='.
+func (p *Parser) isDestructuring() bool {
+	tokens := p.Scanner.Tokens
+	i := p.Current
+	for names := 0; tokens[i].Type == token.IDENTIFIER; names++ {
+		switch tokens[i+1].Type {
+		case token.COMMA:
+			i += 2
+		case token.RIGHT_PAREN:
+			return names > 0 && tokens[i+2].Type == token.EQUAL
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// destructuringAssignment compiles '(a, b) = value', assigning the items of
+// the tuple or list value to the variables. The assignment evaluates to a
+// tuple of the values assigned.
+func (p *Parser) destructuringAssignment() {
+	var names []token.Token
+	for ok := true; ok; ok = p.match(token.COMMA) {
+		p.consume(token.IDENTIFIER, "Expect variable name.")
+		names = append(names, p.PrevToken())
+		p.checkAssignable(p.PrevToken())
+	}
+	p.consume(token.RIGHT_PAREN, "Expect ')' after variable names.")
+	p.consume(token.EQUAL, "Expect '=' after variable names.")
+
+	p.expression()
+	p.emitBytes(repr.OP_UNPACK, byte(len(names)))
+	for i := len(names) - 1; i >= 0; i-- {
+		arg, _, setOp := p.resolveVariable(names[i])
+		p.emitBytes(setOp, arg)
+		p.emitByte(repr.OP_POP)
+	}
+	for _, name := range names {
+		arg, getOp, _ := p.resolveVariable(name)
+		p.emitBytes(getOp, arg)
+	}
+	p.emitTuple(len(names))
+}
+
 func (p *Parser) ifStatement() {
 	p.consume(token.LEFT_PAREN, "Expect '(' after 'if'.")
 	p.expression()
@@ -463,6 +1049,109 @@ func (p *Parser) ifStatement() {
 	p.patchJump(elseJump)
 }
 
+// importDeclaration compiles 'import "path" as name;', 'import "path";' or
+// 'import { a, b } from "path";'. Each selected name imports the module again,
+// which is cheap once it has been loaded.
+func (p *Parser) importDeclaration() {
+	if p.match(token.LEFT_BRACE) {
+		var names []token.Token
+		for ok := true; ok; ok = p.match(token.COMMA) {
+			p.consume(token.IDENTIFIER, "Expect name to import.")
+			names = append(names, p.PrevToken())
+		}
+		p.consume(token.RIGHT_BRACE, "Expect '}' after imported names.")
+		p.consumeWord("from", "Expect 'from' after imported names.")
+		path := p.modulePath()
+		p.consume(token.SEMICOLON, "Expect ';' after import.")
+
+		for _, name := range names {
+			p.emitBytes(repr.OP_IMPORT, path)
+			p.emitBytes(repr.OP_GET_PROPERTY, p.identifierConstant(name))
+			p.declareVariable(name)
+			p.defineVariable(p.identifierConstant(name))
+		}
+		return
+	}
+
+	path := p.modulePath()
+	p.emitBytes(repr.OP_IMPORT, path)
+	if p.match(token.SEMICOLON) {
+		p.emitByte(repr.OP_POP)
+		return
+	}
+
+	p.consumeWord("as", "Expect 'as' or ';' after module path.")
+	global := p.parseVariable("Expect module name.")
+	p.consume(token.SEMICOLON, "Expect ';' after import.")
+	p.defineVariable(global)
+}
+
+func (p *Parser) modulePath() byte {
+	p.consume(token.STRING, "Expect module path.")
+	return p.makeConstant(repr.StringVal(p.PrevToken().Literal.(string)))
+}
+
+// interpolation compiles a string literal with embedded expressions into
+// the concatenation of its segments and the stringified expressions.
+func (p *Parser) interpolation(canAssign bool) {
+	p.emitConstant(repr.StringVal(p.PrevToken().Literal.(string)))
+	for {
+		p.expression()
+		p.emitBytes(repr.OP_TO_STRING, repr.OP_ADD)
+
+		if !p.match(token.INTERPOLATION) {
+			p.consume(token.STRING, "Expect end of string interpolation.")
+		}
+		if segment := p.PrevToken().Literal.(string); segment != "" {
+			p.emitConstant(repr.StringVal(segment))
+			p.emitByte(repr.OP_ADD)
+		}
+		if p.PrevToken().Type != token.INTERPOLATION {
+			return
+		}
+	}
+}
+
+// isArrowFunction reports whether the parenthesis just consumed opens the
+// parameter list of an arrow function, i.e. whether the matching ')' is
+// followed by '=>' other than the one ending a match guard.
+func (p *Parser) isArrowFunction() bool {
+	depth := 0
+	for i := p.Current; i < len(p.Scanner.Tokens); i++ {
+		switch p.Scanner.Tokens[i].Type {
+		case token.LEFT_PAREN:
+			depth++
+		case token.RIGHT_PAREN:
+			if depth == 0 {
+				return i+1 < len(p.Scanner.Tokens) && p.Scanner.Tokens[i+1].Type == token.ARROW && i+1 != p.guardEnd
+			}
+			depth--
+		case token.EOF:
+			return false
+		}
+	}
+	return false
+}
+
+func (p *Parser) list(canAssign bool) {
+	itemCount := 0
+	for !p.check(token.RIGHT_BRACKET) {
+		p.expression()
+
+		if itemCount == 255 {
+			loxerror.Error(p.CurrToken().Line, "Cannot have more than 255 items in a list literal.")
+		}
+		itemCount++
+
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+
+	p.consume(token.RIGHT_BRACKET, "Expect ']' after list items.")
+	p.emitBytes(repr.OP_BUILD_LIST, byte(itemCount))
+}
+
 func (p *Parser) literal(canAssign bool) {
 	// fmt.Printf("Prev token: %s", p.Scanner.Tokens[p.Current - 1].ValStr())
 	switch p.PrevToken().Type {
@@ -478,9 +1167,49 @@ func (p *Parser) literal(canAssign bool) {
 	}
 }
 
+// mapLiteral compiles a map literal. A '{' only reaches the expression
+// parser where a block is not allowed, so there is no ambiguity.
+func (p *Parser) mapLiteral(canAssign bool) {
+	entryCount := 0
+	for !p.check(token.RIGHT_BRACE) {
+		p.expression()
+		p.consume(token.COLON, "Expect ':' after map key.")
+		p.expression()
+
+		if entryCount == 255 {
+			loxerror.Error(p.CurrToken().Line, "Cannot have more than 255 entries in a map literal.")
+		}
+		entryCount++
+
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+
+	p.consume(token.RIGHT_BRACE, "Expect '}' after map entries.")
+	p.emitBytes(repr.OP_BUILD_MAP, byte(entryCount))
+}
+
+func (p *Parser) method() {
+	p.consume(token.IDENTIFIER, "Expect method name.")
+	constant := p.identifierConstant(p.PrevToken())
+
+	funcType := repr.FUNC_METHOD
+	if p.PrevToken().Lexeme == "init" {
+		funcType = repr.FUNC_INITIALIZER
+	}
+	p.function(funcType)
+
+	p.emitBytes(repr.OP_METHOD, constant)
+}
+
 func (p *Parser) number(canAssign bool) {
-	val := p.PrevToken().Literal.(float64)
-	p.emitConstant(repr.NumberVal(val))
+	switch val := p.PrevToken().Literal.(type) {
+	case int64:
+		p.emitConstant(repr.IntVal(val))
+	case float64:
+		p.emitConstant(repr.NumberVal(val))
+	}
 }
 
 func (p *Parser) or(canAssign bool) {
@@ -494,6 +1223,36 @@ func (p *Parser) or(canAssign bool) {
 	p.patchJump(endJump)
 }
 
+func (p *Parser) postfixIncrement(name token.Token) {
+	p.checkAssignable(name)
+	arg, getOp, setOp := p.resolveVariable(name)
+
+	// The old value stays on the stack as the result of the expression.
+	p.emitBytes(getOp, arg)
+	p.emitBytes(getOp, arg)
+	p.emitIncrement(p.PrevToken().Type)
+	p.emitBytes(setOp, arg)
+	p.emitByte(repr.OP_POP)
+}
+
+func (p *Parser) prefixIncrement(canAssign bool) {
+	operatorType := p.PrevToken().Type
+	if operatorType == token.MINUS_MINUS && !p.check(token.IDENTIFIER) {
+		// Without a variable to decrement, "--" is a double negation.
+		p.parsePrecedence(PREC_UNARY)
+		p.emitBytes(repr.OP_NEGATE, repr.OP_NEGATE)
+		return
+	}
+
+	p.consume(token.IDENTIFIER, "Expect variable name after '"+p.PrevToken().Lexeme+"'.")
+	p.checkAssignable(p.PrevToken())
+	arg, getOp, setOp := p.resolveVariable(p.PrevToken())
+
+	p.emitBytes(getOp, arg)
+	p.emitIncrement(operatorType)
+	p.emitBytes(setOp, arg)
+}
+
 func (p *Parser) printStatement() {
 	p.expression()
 	p.consume(token.SEMICOLON, "Expect ; after value.")
@@ -505,23 +1264,77 @@ func (p *Parser) returnStatement() {
 		loxerror.Error(p.CurrToken().Line, "Cannot return from top-level code.")
 	}
 	if p.match(token.SEMICOLON) {
+		p.exitTryBlocks(nil)
 		p.emitReturn()
 	} else {
+		if p.Compiler.Type == repr.FUNC_INITIALIZER {
+			loxerror.Error(p.PrevToken().Line, "Cannot return a value from an initializer.")
+		}
+
 		p.expression()
+		if p.match(token.COMMA) {
+			itemCount := 1
+			for ok := true; ok; ok = p.match(token.COMMA) {
+				p.expression()
+				itemCount++
+			}
+			p.emitTuple(itemCount)
+		}
 		p.consume(token.SEMICOLON, "Expect ';' after return value.")
+
+		if p.Compiler.Try != nil {
+			// The result is kept in a hidden local while finally blocks run.
+			p.addLocal(p.syntheticToken(""))
+			p.Compiler.Locals[len(p.Compiler.Locals)-1].Depth = p.Compiler.ScopeDepth
+			p.exitTryBlocks(nil)
+			p.Compiler.Locals = p.Compiler.Locals[:len(p.Compiler.Locals)-1]
+		}
 		p.emitByte(repr.OP_RETURN)
 	}
 }
 
+// yield compiles 'yield value', which suspends the generator until it is
+// resumed and evaluates to the value it is resumed with. The value yielded
+// is nil when omitted.
+func (p *Parser) yield(canAssign bool) {
+	if p.Compiler.Type == repr.FUNC_SCRIPT {
+		loxerror.Error(p.PrevToken().Line, "Cannot yield from top-level code.")
+	} else if p.Compiler.Type == repr.FUNC_INITIALIZER {
+		loxerror.Error(p.PrevToken().Line, "Cannot yield from an initializer.")
+	}
+	p.Compiler.Function.Generator = true
+
+	if p.getRule(p.CurrToken().Type).Prefix != nil {
+		p.parsePrecedence(PREC_ASSIGNMENT)
+	} else {
+		p.emitByte(repr.OP_NIL)
+	}
+	p.emitByte(repr.OP_YIELD)
+}
+
 func (p *Parser) statement() {
 	if p.match(token.PRINT) {
 		p.printStatement()
+	} else if p.match(token.ASSERT) {
+		p.assertStatement()
+	} else if p.match(token.BREAK) {
+		p.breakStatement()
+	} else if p.match(token.CONTINUE) {
+		p.continueStatement()
 	} else if p.match(token.FOR) {
 		p.forStatement()
 	} else if p.match(token.IF) {
 		p.ifStatement()
+	} else if p.match(token.MATCH) {
+		p.matchStatement()
 	} else if p.match(token.RETURN) {
 		p.returnStatement()
+	} else if p.match(token.SPAWN) {
+		p.spawnStatement()
+	} else if p.match(token.THROW) {
+		p.throwStatement()
+	} else if p.match(token.TRY) {
+		p.tryStatement()
 	} else if p.match(token.LEFT_BRACE) {
 		p.beginScope()
 		p.block()
@@ -538,6 +1351,220 @@ func (p *Parser) string(canAssign bool) {
 	p.emitConstant(repr.StringVal(val))
 }
 
+func (p *Parser) subscript(canAssign bool) {
+	// A missing slice bound is compiled as nil and filled in by the VM.
+	if p.match(token.COLON) {
+		p.emitByte(repr.OP_NIL)
+		p.sliceEnd()
+		return
+	}
+
+	p.expression()
+	if p.match(token.COLON) {
+		p.sliceEnd()
+		return
+	}
+	p.consume(token.RIGHT_BRACKET, "Expect ']' after index.")
+
+	if canAssign && p.match(token.EQUAL) {
+		p.expression()
+		p.emitByte(repr.OP_INDEX_SET)
+	} else {
+		p.emitByte(repr.OP_INDEX_GET)
+	}
+}
+
+func (p *Parser) sliceEnd() {
+	if p.check(token.RIGHT_BRACKET) {
+		p.emitByte(repr.OP_NIL)
+	} else {
+		p.expression()
+	}
+	p.consume(token.RIGHT_BRACKET, "Expect ']' after slice.")
+	p.emitByte(repr.OP_SLICE)
+}
+
+func (p *Parser) super(canAssign bool) {
+	if p.ClassCompiler == nil {
+		loxerror.Error(p.PrevToken().Line, "Cannot use 'super' outside of a class.")
+	} else if !p.ClassCompiler.HasSuperclass {
+		loxerror.Error(p.PrevToken().Line, "Cannot use 'super' in a class with no superclass.")
+	}
+
+	p.consume(token.DOT, "Expect '.' after 'super'.")
+	p.consume(token.IDENTIFIER, "Expect superclass method name.")
+	name := p.identifierConstant(p.PrevToken())
+
+	p.namedVariable(p.syntheticToken("this"), false)
+	if p.match(token.LEFT_PAREN) {
+		if p.hasExtendedArguments() || p.Spawning {
+			p.namedVariable(p.syntheticToken("super"), false)
+			p.emitBytes(repr.OP_GET_SUPER, name)
+			p.emitCall(p.argumentList())
+			return
+		}
+		argCount, _, _ := p.argumentList()
+		p.namedVariable(p.syntheticToken("super"), false)
+		p.emitBytes(repr.OP_SUPER_INVOKE, name)
+		p.emitByte(argCount)
+	} else {
+		p.namedVariable(p.syntheticToken("super"), false)
+		p.emitBytes(repr.OP_GET_SUPER, name)
+	}
+}
+
+func (p *Parser) ternary(canAssign bool) {
+	elseJump := p.emitJump(repr.OP_JUMP_IF_FALSE)
+	p.emitByte(repr.OP_POP)
+	p.expression()
+	p.consume(token.COLON, "Expect ':' after then branch of conditional expression.")
+
+	endJump := p.emitJump(repr.OP_JUMP)
+	p.patchJump(elseJump)
+	p.emitByte(repr.OP_POP)
+
+	// The else branch is right-associative so conditionals can be chained.
+	p.parsePrecedence(PREC_TERNARY)
+	p.patchJump(endJump)
+}
+
+func (p *Parser) this(canAssign bool) {
+	if p.ClassCompiler == nil {
+		loxerror.Error(p.PrevToken().Line, "Cannot use 'this' outside of a class.")
+		return
+	}
+
+	p.namedVariable(p.PrevToken(), false)
+}
+
+// spawnStatement compiles 'spawn f(args);'. The call is compiled as usual,
+// and its OP_CALL then replaced by OP_SPAWN, which runs it on a new fiber.
+func (p *Parser) spawnStatement() {
+	spawning := p.Spawning
+	p.Spawning = true
+	p.lastCall = -1
+	p.expression()
+	p.Spawning = spawning
+
+	code := p.CurrChunk().Code
+	switch {
+	case p.lastCall == -1:
+		loxerror.Error(p.PrevToken().Line, "Expect function call after 'spawn'.")
+	case code[p.lastCall] == repr.OP_CALL && p.lastCall+2 == len(code):
+		code[p.lastCall] = repr.OP_SPAWN
+	case code[p.lastCall] != repr.OP_CALL && p.lastCall+3 == len(code):
+		loxerror.Error(p.PrevToken().Line, "Cannot spawn a call with named or spread arguments.")
+	default:
+		loxerror.Error(p.PrevToken().Line, "Expect function call after 'spawn'.")
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after spawned call.")
+}
+
+// assertStatement compiles 'assert condition, message;', where the message
+// is optional. A failing assert raises an error quoting its line and the
+// source of the condition; the message is only evaluated then.
+func (p *Parser) assertStatement() {
+	chunk := p.CurrChunk()
+	codeStart := len(chunk.Code)
+	line := p.PrevToken().Line
+
+	first := p.Current
+	p.expression()
+	last := p.Current - 1
+	source := p.Scanner.Source[p.Scanner.Offsets[first] : p.Scanner.Offsets[last]+len(p.Scanner.Tokens[last].Lexeme)]
+	failure := fmt.Sprintf("Assertion '%s' failed at line %d", source, line)
+
+	failJump := p.emitJump(repr.OP_JUMP_IF_FALSE)
+	p.emitByte(repr.OP_POP)
+	endJump := p.emitJump(repr.OP_JUMP)
+	p.patchJump(failJump)
+	p.emitByte(repr.OP_POP)
+	if p.match(token.COMMA) {
+		p.expression()
+	} else {
+		p.emitByte(repr.OP_NIL)
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after assert.")
+	p.emitBytes(repr.OP_ASSERT, p.makeConstant(repr.StringVal(failure)))
+	p.patchJump(endJump)
+
+	if p.StripAsserts {
+		chunk.Code = chunk.Code[:codeStart]
+		chunk.Lines = chunk.Lines[:codeStart]
+	}
+}
+
+func (p *Parser) throwStatement() {
+	p.expression()
+	p.consume(token.SEMICOLON, "Expect ';' after thrown value.")
+	p.emitByte(repr.OP_THROW)
+}
+
+func (p *Parser) tryStatement() {
+	p.consume(token.LEFT_BRACE, "Expect '{' after 'try'.")
+	try := p.beginTry(p.findFinally(p.Current - 1))
+
+	p.beginScope()
+	p.block()
+	p.endScope()
+	p.closeSegment(try)
+	tryExit := p.emitJump(repr.OP_JUMP)
+
+	// The handlers below start with the exception on top of the stack, just
+	// above the locals that were in scope when the try statement began.
+	catchTarget, catchExit := -1, -1
+	if p.match(token.CATCH) {
+		catchTarget = len(p.CurrChunk().Code)
+		try.InCatch = true
+		try.SegmentStart = catchTarget
+
+		p.beginScope()
+		p.consume(token.LEFT_PAREN, "Expect '(' after 'catch'.")
+		p.consume(token.IDENTIFIER, "Expect exception variable name.")
+		p.declareVariable(p.PrevToken())
+		p.markInitialized()
+		p.consume(token.RIGHT_PAREN, "Expect ')' after exception variable.")
+		p.consume(token.LEFT_BRACE, "Expect '{' before catch body.")
+		p.block()
+		p.endScope()
+
+		p.closeSegment(try)
+		catchExit = p.emitJump(repr.OP_JUMP)
+	}
+	p.Compiler.Try = try.Enclosing
+
+	// Exceptions escaping the body or the catch clause run a copy of the
+	// finally block and are then thrown again.
+	finallyTarget := -1
+	if try.FinallyStart != -1 {
+		finallyTarget = len(p.CurrChunk().Code)
+
+		p.beginScope()
+		p.addLocal(p.syntheticToken(""))
+		p.markInitialized()
+		p.inlineFinally(try)
+		p.emitBytes(repr.OP_GET_LOCAL, byte(try.Locals))
+		p.emitByte(repr.OP_THROW)
+		p.Compiler.Locals = p.Compiler.Locals[:try.Locals]
+		p.Compiler.ScopeDepth--
+	} else if catchTarget == -1 {
+		loxerror.Error(p.CurrToken().Line, "Expect 'catch' or 'finally' after try block.")
+	}
+
+	p.patchJump(tryExit)
+	if catchExit != -1 {
+		p.patchJump(catchExit)
+	}
+	if p.match(token.FINALLY) {
+		p.consume(token.LEFT_BRACE, "Expect '{' after 'finally'.")
+		p.beginScope()
+		p.block()
+		p.endScope()
+	}
+
+	p.addHandlers(try, catchTarget, finallyTarget)
+}
+
 func (p *Parser) unary(canAssign bool) {
 	operatorType := p.PrevToken().Type
 	p.parsePrecedence(PREC_UNARY)
@@ -546,16 +1573,28 @@ func (p *Parser) unary(canAssign bool) {
 		p.emitByte(repr.OP_NOT)
 	case token.MINUS:
 		p.emitByte(repr.OP_NEGATE)
+	case token.TILDE:
+		p.emitByte(repr.OP_BIT_NOT)
 	default:
 		return
 	}
 }
 
 func (p *Parser) variable(canAssign bool) {
-	p.namedVariable(p.PrevToken(), canAssign)
+	name := p.PrevToken()
+	if p.match(token.PLUS_PLUS) || p.match(token.MINUS_MINUS) {
+		p.postfixIncrement(name)
+		return
+	}
+	p.namedVariable(name, canAssign)
 }
 
 func (p *Parser) varDeclaration() {
+	if p.match(token.LEFT_PAREN) {
+		p.destructuringDeclaration()
+		return
+	}
+
 	global := p.parseVariable("Expect variable name")
 	if p.match(token.EQUAL) {
 		p.expression()
@@ -566,6 +1605,48 @@ func (p *Parser) varDeclaration() {
 	p.defineVariable(global)
 }
 
+// destructuringDeclaration compiles 'var (a, b) = value;', declaring a
+// variable for each item of the tuple or list value.
+func (p *Parser) destructuringDeclaration() {
+	var globals []byte
+	for ok := true; ok; ok = p.match(token.COMMA) {
+		globals = append(globals, p.parseVariable("Expect variable name."))
+	}
+	p.consume(token.RIGHT_PAREN, "Expect ')' after variable names.")
+	p.consume(token.EQUAL, "Expect '=' after variable names.")
+	p.expression()
+	p.consume(token.SEMICOLON, "Expect ';' after variable declaration.")
+	p.emitBytes(repr.OP_UNPACK, byte(len(globals)))
+
+	if p.Compiler.ScopeDepth > 0 {
+		locals := p.Compiler.Locals[len(p.Compiler.Locals)-len(globals):]
+		for i := range locals {
+			locals[i].Depth = p.Compiler.ScopeDepth
+		}
+		return
+	}
+	for i := len(globals) - 1; i >= 0; i-- {
+		p.emitBytes(repr.OP_DEFINE_GLOBAL, globals[i])
+	}
+}
+
+func (p *Parser) constDeclaration() {
+	global := p.parseVariable("Expect constant name.")
+	name := p.PrevToken()
+	p.consume(token.EQUAL, "Expect '=' after constant name.")
+	p.expression()
+	p.consume(token.SEMICOLON, "Expect ';' after constant declaration.")
+
+	if p.Compiler.ScopeDepth > 0 {
+		p.Compiler.Locals[len(p.Compiler.Locals)-1].IsConst = true
+		p.markInitialized()
+		return
+	}
+
+	p.Constants[name.Lexeme] = true
+	p.emitBytes(repr.OP_DEFINE_CONST, global)
+}
+
 func (p *Parser) whileStatement() {
 	loopStart := len(p.CurrChunk().Code)
 
@@ -576,10 +1657,12 @@ func (p *Parser) whileStatement() {
 	exitJump := p.emitJump(repr.OP_JUMP_IF_FALSE)
 
 	p.emitByte(repr.OP_POP)
+	p.beginLoop(loopStart)
 	p.statement()
 
 	p.emitLoop(loopStart)
 
 	p.patchJump(exitJump)
 	p.emitByte(repr.OP_POP)
+	p.endLoop()
 }
@@ -5,13 +5,19 @@ import "golox/token"
 const (
 	PREC_NONE       = iota
 	PREC_ASSIGNMENT // =
+	PREC_TERNARY    // ?:
 	PREC_OR         // or
 	PREC_AND        // and
 	PREC_EQUALITY   // == !==
 	PREC_COMPARISON // < > <= >=
+	PREC_BIT_OR     // |
+	PREC_BIT_XOR    // ^
+	PREC_BIT_AND    // &
+	PREC_SHIFT      // << >>
 	PREC_TERM       // + -
-	PREC_FACTOR     // * /
-	PREC_UNARY      // ! -
+	PREC_FACTOR     // * / ~/ %
+	PREC_UNARY      // ! - ~
+	PREC_EXPONENT   // **
 	PREC_CALL       // . () []
 	PREC_PRIMARY
 )
@@ -29,45 +35,84 @@ var rules = make(map[token.Type]*ParseRule)
 func (p *Parser) InitRules() {
 	rules[token.LEFT_PAREN] = &ParseRule{p.grouping, p.call, PREC_CALL}
 	rules[token.RIGHT_PAREN] = &ParseRule{nil, nil, PREC_NONE}
-	rules[token.LEFT_BRACE] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.LEFT_BRACE] = &ParseRule{p.mapLiteral, nil, PREC_NONE}
 	rules[token.RIGHT_BRACE] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.LEFT_BRACKET] = &ParseRule{p.list, p.subscript, PREC_CALL}
+	rules[token.RIGHT_BRACKET] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.COLON] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.QUESTION] = &ParseRule{nil, p.ternary, PREC_TERNARY}
 	rules[token.COMMA] = &ParseRule{nil, nil, PREC_NONE}
-	rules[token.DOT] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.DOT] = &ParseRule{nil, p.dot, PREC_CALL}
 	rules[token.MINUS] = &ParseRule{p.unary, p.binary, PREC_TERM}
 	rules[token.PLUS] = &ParseRule{nil, p.binary, PREC_TERM}
 	rules[token.SEMICOLON] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.SLASH] = &ParseRule{nil, p.binary, PREC_FACTOR}
 	rules[token.STAR] = &ParseRule{nil, p.binary, PREC_FACTOR}
+	rules[token.PERCENT] = &ParseRule{nil, p.binary, PREC_FACTOR}
+	rules[token.AMPERSAND] = &ParseRule{nil, p.binary, PREC_BIT_AND}
+	rules[token.PIPE] = &ParseRule{nil, p.binary, PREC_BIT_OR}
+	rules[token.CARET] = &ParseRule{nil, p.binary, PREC_BIT_XOR}
+	rules[token.TILDE] = &ParseRule{p.unary, nil, PREC_NONE}
 
 	rules[token.BANG] = &ParseRule{p.unary, nil, PREC_NONE}
 	rules[token.BANG_EQUAL] = &ParseRule{nil, p.binary, PREC_EQUALITY}
 	rules[token.EQUAL] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.EQUAL_EQUAL] = &ParseRule{nil, p.binary, PREC_EQUALITY}
+	rules[token.ARROW] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.GREATER] = &ParseRule{nil, p.binary, PREC_COMPARISON}
 	rules[token.GREATER_EQUAL] = &ParseRule{nil, p.binary, PREC_COMPARISON}
 	rules[token.LESS] = &ParseRule{nil, p.binary, PREC_COMPARISON}
 	rules[token.LESS_EQUAL] = &ParseRule{nil, p.binary, PREC_COMPARISON}
+	rules[token.TILDE_SLASH] = &ParseRule{nil, p.binary, PREC_FACTOR}
+	rules[token.STAR_STAR] = &ParseRule{nil, p.binary, PREC_EXPONENT}
+	rules[token.LESS_LESS] = &ParseRule{nil, p.binary, PREC_SHIFT}
+	rules[token.GREATER_GREATER] = &ParseRule{nil, p.binary, PREC_SHIFT}
+	rules[token.PLUS_EQUAL] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.MINUS_EQUAL] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.STAR_EQUAL] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.SLASH_EQUAL] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.PERCENT_EQUAL] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.ELLIPSIS] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.PLUS_PLUS] = &ParseRule{p.prefixIncrement, nil, PREC_NONE}
+	rules[token.MINUS_MINUS] = &ParseRule{p.prefixIncrement, nil, PREC_NONE}
 
 	rules[token.IDENTIFIER] = &ParseRule{p.variable, nil, PREC_NONE}
 	rules[token.STRING] = &ParseRule{p.string, nil, PREC_NONE}
+	rules[token.INTERPOLATION] = &ParseRule{p.interpolation, nil, PREC_NONE}
 	rules[token.NUMBER] = &ParseRule{p.number, nil, PREC_NONE}
 
 	rules[token.AND] = &ParseRule{nil, p.and, PREC_AND}
+	rules[token.ASSERT] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.BREAK] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.CASE] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.CATCH] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.CLASS] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.CONST] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.CONTINUE] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.ELSE] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.ENUM] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.EXPORT] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.FALSE] = &ParseRule{p.literal, nil, PREC_NONE}
+	rules[token.FINALLY] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.FOR] = &ParseRule{nil, nil, PREC_NONE}
-	rules[token.FUN] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.FUN] = &ParseRule{p.funExpression, nil, PREC_NONE}
 	rules[token.IF] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.IMPORT] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.IN] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.MATCH] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.NIL] = &ParseRule{p.literal, nil, PREC_NONE}
 	rules[token.OR] = &ParseRule{nil, p.or, PREC_OR}
 	rules[token.PRINT] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.RETURN] = &ParseRule{nil, nil, PREC_NONE}
-	rules[token.SUPER] = &ParseRule{nil, nil, PREC_NONE}
-	rules[token.THIS] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.SPAWN] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.SUPER] = &ParseRule{p.super, nil, PREC_NONE}
+	rules[token.THIS] = &ParseRule{p.this, nil, PREC_NONE}
+	rules[token.THROW] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.TRUE] = &ParseRule{p.literal, nil, PREC_NONE}
+	rules[token.TRY] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.VAR] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.WHILE] = &ParseRule{nil, nil, PREC_NONE}
+	rules[token.YIELD] = &ParseRule{p.yield, nil, PREC_NONE}
 
 	rules[token.ILLEGAL] = &ParseRule{nil, nil, PREC_NONE}
 	rules[token.EOF] = &ParseRule{nil, nil, PREC_NONE}
@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"golox/repr"
+	"golox/token"
+)
+
+// TryBlock tracks a try statement whose body or catch clause is being
+// compiled. The code it protects is recorded as a list of segments because
+// the copies of finally blocks inlined for return, break and continue must
+// not be covered by the handlers of the try statements they leave.
+type TryBlock struct {
+	Enclosing *TryBlock
+	Loop      *Loop
+	Locals    int
+	// FinallyStart is the index of the '{' token opening the finally block,
+	// or -1 if the statement has none.
+	FinallyStart  int
+	InCatch       bool
+	SegmentStart  int
+	TrySegments   []Segment
+	CatchSegments []Segment
+}
+
+type Segment struct {
+	Start int
+	End   int
+}
+
+// beginTry starts tracking a try statement whose body is about to be
+// compiled.
+func (p *Parser) beginTry(finallyStart int) *TryBlock {
+	try := &TryBlock{
+		Enclosing:    p.Compiler.Try,
+		Loop:         p.Compiler.Loop,
+		Locals:       len(p.Compiler.Locals),
+		FinallyStart: finallyStart,
+		SegmentStart: len(p.CurrChunk().Code),
+	}
+	p.Compiler.Try = try
+	return try
+}
+
+// closeSegment ends the segment of code protected by try at the current
+// position.
+func (p *Parser) closeSegment(try *TryBlock) {
+	segment := Segment{try.SegmentStart, len(p.CurrChunk().Code)}
+	if segment.Start == segment.End {
+		return
+	}
+
+	if try.InCatch {
+		try.CatchSegments = append(try.CatchSegments, segment)
+	} else {
+		try.TrySegments = append(try.TrySegments, segment)
+	}
+}
+
+// addHandlers records the handlers of a finished try statement in the chunk.
+// A target of -1 means the statement has no such clause.
+func (p *Parser) addHandlers(try *TryBlock, catchTarget, finallyTarget int) {
+	chunk := p.CurrChunk()
+	for _, segment := range try.TrySegments {
+		if catchTarget != -1 {
+			chunk.Handlers = append(chunk.Handlers, repr.Handler{Start: segment.Start, End: segment.End, Target: catchTarget, Slots: try.Locals})
+		}
+		if finallyTarget != -1 {
+			chunk.Handlers = append(chunk.Handlers, repr.Handler{Start: segment.Start, End: segment.End, Target: finallyTarget, Slots: try.Locals})
+		}
+	}
+
+	if finallyTarget == -1 {
+		return
+	}
+	for _, segment := range try.CatchSegments {
+		chunk.Handlers = append(chunk.Handlers, repr.Handler{Start: segment.Start, End: segment.End, Target: finallyTarget, Slots: try.Locals})
+	}
+}
+
+// exitTryBlocks runs the finally blocks of the try statements left by
+// breaking out of or continuing loop, or of all of them when returning with a
+// nil loop. Each finally block still runs under the handlers of the try
+// statements enclosing it.
+func (p *Parser) exitTryBlocks(loop *Loop) {
+	innermost := p.Compiler.Try
+	var exited []*TryBlock
+	for try := innermost; try != nil && (loop == nil || try.Loop == loop); try = try.Enclosing {
+		p.closeSegment(try)
+		exited = append(exited, try)
+
+		if try.FinallyStart != -1 {
+			p.Compiler.Try = try.Enclosing
+			p.inlineFinally(try)
+		}
+	}
+	p.Compiler.Try = innermost
+
+	// The jump or return following the finally blocks cannot throw, so the
+	// exited statements are protected again from here on.
+	for _, try := range exited {
+		try.SegmentStart = len(p.CurrChunk().Code)
+	}
+}
+
+// inlineFinally compiles a copy of the finally block of try at the current
+// position. Locals declared since the try statement began are hidden so that
+// names in the copy resolve as they do in the original block.
+func (p *Parser) inlineFinally(try *TryBlock) {
+	hidden := make([]bool, len(p.Compiler.Locals)-try.Locals)
+	for i := range hidden {
+		hidden[i] = p.Compiler.Locals[try.Locals+i].Hidden
+		p.Compiler.Locals[try.Locals+i].Hidden = true
+	}
+
+	current := p.Current
+	p.Current = try.FinallyStart + 1
+	p.beginScope()
+	p.block()
+	p.endScope()
+	p.Current = current
+
+	for i := range hidden {
+		p.Compiler.Locals[try.Locals+i].Hidden = hidden[i]
+	}
+}
+
+// findFinally returns the index of the '{' opening the finally block of the
+// try statement whose body opens at the token index start, or -1.
+func (p *Parser) findFinally(start int) int {
+	tokens := p.Scanner.Tokens
+	i := p.skipBlock(start)
+	if tokens[i].Type == token.CATCH {
+		for tokens[i].Type != token.LEFT_BRACE && tokens[i].Type != token.EOF {
+			i++
+		}
+		i = p.skipBlock(i)
+	}
+
+	if tokens[i].Type == token.FINALLY && tokens[i+1].Type == token.LEFT_BRACE {
+		return i + 1
+	}
+	return -1
+}
+
+// skipBlock returns the index of the token following the block that opens
+// at the token index start.
+func (p *Parser) skipBlock(start int) int {
+	tokens := p.Scanner.Tokens
+	depth := 0
+	for i := start; tokens[i].Type != token.EOF; i++ {
+		switch tokens[i].Type {
+		case token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_BRACE:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(tokens) - 1
+}
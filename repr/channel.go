@@ -0,0 +1,88 @@
+package repr
+
+// Channel passes values between fibers. Sending blocks until a receiver
+// takes the value, unless there is room left in the channel's buffer.
+type Channel struct {
+	Capacity  int
+	Buffer    []Value
+	Closed    bool
+	Senders   []*Waiter
+	Receivers []*Waiter
+}
+
+// Waiter is a fiber blocked on a channel, along with the value it is sending.
+// Wake resumes the fiber with the value it received, or nil after sending,
+// and reports false if the fiber has stopped waiting, as happens to a select
+// that another channel has already woken.
+type Waiter struct {
+	Value Value
+	Wake  func(value Value) bool
+}
+
+func NewChannel(capacity int) *Channel {
+	return &Channel{capacity, []Value{}, false, []*Waiter{}, []*Waiter{}}
+}
+
+// TrySend sends value without blocking, either to a waiting receiver or into
+// the buffer, and reports whether it could.
+func (c *Channel) TrySend(value Value) bool {
+	for len(c.Receivers) > 0 {
+		receiver := c.Receivers[0]
+		c.Receivers = c.Receivers[1:]
+		if receiver.Wake(value) {
+			return true
+		}
+	}
+
+	if len(c.Buffer) < c.Capacity {
+		c.Buffer = append(c.Buffer, value)
+		return true
+	}
+	return false
+}
+
+// TryReceive receives a value without blocking and reports whether it could.
+// A closed channel with nothing left to receive gives nil.
+func (c *Channel) TryReceive() (Value, bool) {
+	if len(c.Buffer) > 0 {
+		value := c.Buffer[0]
+		c.Buffer = c.Buffer[1:]
+		// The room made in the buffer goes to the first waiting sender.
+		if sender, ok := c.nextSender(); ok {
+			c.Buffer = append(c.Buffer, sender.Value)
+		}
+		return value, true
+	}
+
+	if sender, ok := c.nextSender(); ok {
+		return sender.Value, true
+	}
+	if c.Closed {
+		return NilVal(), true
+	}
+	return NilVal(), false
+}
+
+func (c *Channel) nextSender() (*Waiter, bool) {
+	for len(c.Senders) > 0 {
+		sender := c.Senders[0]
+		c.Senders = c.Senders[1:]
+		if sender.Wake(NilVal()) {
+			return sender, true
+		}
+	}
+	return nil, false
+}
+
+// Close closes the channel, waking every waiting receiver with nil.
+func (c *Channel) Close() {
+	c.Closed = true
+	for _, receiver := range c.Receivers {
+		receiver.Wake(NilVal())
+	}
+	c.Receivers = []*Waiter{}
+}
+
+func (c *Channel) String() string {
+	return "<channel>"
+}
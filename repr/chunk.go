@@ -15,7 +15,20 @@ const (
 	OP_SET_LOCAL
 	OP_GET_GLOBAL
 	OP_DEFINE_GLOBAL
+	OP_DEFINE_CONST
 	OP_SET_GLOBAL
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_GET_SUPER
+	OP_BUILD_LIST
+	OP_BUILD_MAP
+	OP_BUILD_TUPLE
+	OP_INDEX_GET
+	OP_INDEX_SET
+	OP_SLICE
+	OP_UNPACK
 	OP_EQUAL
 	OP_GREATER
 	OP_LESS
@@ -23,23 +36,65 @@ const (
 	OP_SUBTRACT
 	OP_MULTIPLY
 	OP_DIVIDE
+	OP_FLOOR_DIVIDE
+	OP_MODULO
+	OP_POWER
+	OP_BIT_AND
+	OP_BIT_OR
+	OP_BIT_XOR
+	OP_SHIFT_LEFT
+	OP_SHIFT_RIGHT
 	OP_NOT
 	OP_NEGATE
+	OP_BIT_NOT
+	OP_TO_STRING
 	OP_PRINT
 	OP_JUMP
 	OP_JUMP_IF_FALSE
+	OP_JUMP_IF_PRESENT
 	OP_LOOP
+	OP_GET_ITER
+	OP_FOR_ITER
 	OP_CALL
+	OP_CALL_NAMED
+	OP_CALL_SPREAD
+	OP_SPAWN
+	OP_INVOKE
+	OP_SUPER_INVOKE
+	OP_CLOSURE
+	OP_CLOSE_UPVALUE
 	OP_RETURN
+	OP_THROW
+	OP_ASSERT
+	OP_YIELD
+	OP_CLASS
+	OP_INHERIT
+	OP_METHOD
+	OP_ENUM
+	OP_IMPORT
 )
 
 type Chunk struct {
 	Code      []byte
 	Constants []Value
+	// Lines holds the source line of every byte in Code.
+	Lines    []int
+	Handlers []Handler
+}
+
+// Handler catches exceptions raised by the instructions in [Start, End). The
+// stack is cut back to the frame's first Slots values, the exception is
+// pushed and execution continues at Target. Handlers of nested try blocks
+// come before the handlers of the blocks enclosing them.
+type Handler struct {
+	Start  int
+	End    int
+	Target int
+	Slots  int
 }
 
 func NewChunk() *Chunk {
-	return &Chunk{[]byte{}, []Value{}}
+	return &Chunk{[]byte{}, []Value{}, []int{}, []Handler{}}
 }
 
 func (c *Chunk) String() string {
@@ -50,6 +105,9 @@ func (c *Chunk) String() string {
 	}
 
 	sb.WriteString("]\n\n")
+	for _, handler := range c.Handlers {
+		sb.WriteString(fmt.Sprintf("Handler [%d, %d) -> %d\n", handler.Start, handler.End, handler.Target))
+	}
 	ip := 0
 	for ip < len(c.Code) {
 		sb.WriteString(fmt.Sprintf("\t%3d ", c.Code[ip]))
@@ -82,10 +140,50 @@ func (c *Chunk) String() string {
 			ip++
 			constant := c.Constants[c.Code[ip]]
 			sb.WriteString(fmt.Sprintf("DEFINE_GLOBAL %v\n", constant))
+		case OP_DEFINE_CONST:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("DEFINE_CONST %v\n", constant))
 		case OP_SET_GLOBAL:
 			ip++
 			constant := c.Constants[c.Code[ip]]
 			sb.WriteString(fmt.Sprintf("SET_GLOBAL %v\n", constant))
+		case OP_GET_UPVALUE:
+			ip++
+			sb.WriteString(fmt.Sprintf("GET_UPVALUE ^%d\n", c.Code[ip]))
+		case OP_SET_UPVALUE:
+			ip++
+			sb.WriteString(fmt.Sprintf("SET_UPVALUE ^%d\n", c.Code[ip]))
+		case OP_GET_PROPERTY:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("GET_PROPERTY %v\n", constant))
+		case OP_SET_PROPERTY:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("SET_PROPERTY %v\n", constant))
+		case OP_GET_SUPER:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("GET_SUPER %v\n", constant))
+		case OP_BUILD_LIST:
+			ip++
+			sb.WriteString(fmt.Sprintf("BUILD_LIST %d\n", c.Code[ip]))
+		case OP_BUILD_MAP:
+			ip++
+			sb.WriteString(fmt.Sprintf("BUILD_MAP %d\n", c.Code[ip]))
+		case OP_BUILD_TUPLE:
+			ip++
+			sb.WriteString(fmt.Sprintf("BUILD_TUPLE %d\n", c.Code[ip]))
+		case OP_INDEX_GET:
+			sb.WriteString("INDEX_GET\n")
+		case OP_INDEX_SET:
+			sb.WriteString("INDEX_SET\n")
+		case OP_SLICE:
+			sb.WriteString("SLICE\n")
+		case OP_UNPACK:
+			ip++
+			sb.WriteString(fmt.Sprintf("UNPACK %d\n", c.Code[ip]))
 		case OP_EQUAL:
 			sb.WriteString("EQUAL\n")
 		case OP_GREATER:
@@ -100,10 +198,30 @@ func (c *Chunk) String() string {
 			sb.WriteString("MULTIPLY\n")
 		case OP_DIVIDE:
 			sb.WriteString("DIVIDE\n")
+		case OP_FLOOR_DIVIDE:
+			sb.WriteString("FLOOR_DIVIDE\n")
+		case OP_MODULO:
+			sb.WriteString("MODULO\n")
+		case OP_POWER:
+			sb.WriteString("POWER\n")
+		case OP_BIT_AND:
+			sb.WriteString("BIT_AND\n")
+		case OP_BIT_OR:
+			sb.WriteString("BIT_OR\n")
+		case OP_BIT_XOR:
+			sb.WriteString("BIT_XOR\n")
+		case OP_SHIFT_LEFT:
+			sb.WriteString("SHIFT_LEFT\n")
+		case OP_SHIFT_RIGHT:
+			sb.WriteString("SHIFT_RIGHT\n")
 		case OP_NOT:
 			sb.WriteString("NOT\n")
 		case OP_NEGATE:
 			sb.WriteString("NEGATE\n")
+		case OP_BIT_NOT:
+			sb.WriteString("BIT_NOT\n")
+		case OP_TO_STRING:
+			sb.WriteString("TO_STRING\n")
 		case OP_PRINT:
 			sb.WriteString("PRINT\n")
 		case OP_JUMP:
@@ -114,16 +232,87 @@ func (c *Chunk) String() string {
 			ip += 2
 			jumpLen := int(c.Code[ip])<<8 | int(c.Code[ip-1])
 			sb.WriteString(fmt.Sprintf("JUMP_IF_FALSE %d\n", jumpLen))
+		case OP_JUMP_IF_PRESENT:
+			ip += 3
+			jumpLen := int(c.Code[ip])<<8 | int(c.Code[ip-1])
+			sb.WriteString(fmt.Sprintf("JUMP_IF_PRESENT &%d %d\n", c.Code[ip-2], jumpLen))
 		case OP_LOOP:
 			ip += 2
 			jumpLen := int(c.Code[ip])<<8 | int(c.Code[ip-1])
 			sb.WriteString(fmt.Sprintf("LOOP %d\n", jumpLen))
+		case OP_GET_ITER:
+			sb.WriteString("GET_ITER\n")
+		case OP_FOR_ITER:
+			ip += 2
+			jumpLen := int(c.Code[ip])<<8 | int(c.Code[ip-1])
+			sb.WriteString(fmt.Sprintf("FOR_ITER %d\n", jumpLen))
 		case OP_CALL:
 			ip++
 			argCount := c.Code[ip]
 			sb.WriteString(fmt.Sprintf("CALL %d\n", argCount))
+		case OP_SPAWN:
+			ip++
+			argCount := c.Code[ip]
+			sb.WriteString(fmt.Sprintf("SPAWN %d\n", argCount))
+		case OP_CALL_NAMED:
+			ip += 2
+			names := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("CALL_NAMED %d %v\n", c.Code[ip-1], names))
+		case OP_CALL_SPREAD:
+			ip += 2
+			spreads := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("CALL_SPREAD %d %v\n", c.Code[ip-1], spreads))
+		case OP_INVOKE:
+			ip += 2
+			constant := c.Constants[c.Code[ip-1]]
+			sb.WriteString(fmt.Sprintf("INVOKE %v %d\n", constant, c.Code[ip]))
+		case OP_SUPER_INVOKE:
+			ip += 2
+			constant := c.Constants[c.Code[ip-1]]
+			sb.WriteString(fmt.Sprintf("SUPER_INVOKE %v %d\n", constant, c.Code[ip]))
+		case OP_CLOSURE:
+			ip++
+			function := c.Constants[c.Code[ip]].AsFunction()
+			sb.WriteString(fmt.Sprintf("CLOSURE %v\n", function))
+			for i := 0; i < function.UpvalueCount; i++ {
+				isLocal, index := c.Code[ip+1], c.Code[ip+2]
+				ip += 2
+				if isLocal == 1 {
+					sb.WriteString(fmt.Sprintf("\t      local &%d\n", index))
+				} else {
+					sb.WriteString(fmt.Sprintf("\t      upvalue ^%d\n", index))
+				}
+			}
+		case OP_CLOSE_UPVALUE:
+			sb.WriteString("CLOSE_UPVALUE\n")
 		case OP_RETURN:
 			sb.WriteString("RETURN\n")
+		case OP_THROW:
+			sb.WriteString("THROW\n")
+		case OP_ASSERT:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("ASSERT %v\n", constant))
+		case OP_YIELD:
+			sb.WriteString("YIELD\n")
+		case OP_CLASS:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("CLASS %v\n", constant))
+		case OP_INHERIT:
+			sb.WriteString("INHERIT\n")
+		case OP_METHOD:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("METHOD %v\n", constant))
+		case OP_ENUM:
+			constant := c.Constants[c.Code[ip+1]]
+			ip += 2
+			sb.WriteString(fmt.Sprintf("ENUM %v %d\n", constant, c.Code[ip]))
+		case OP_IMPORT:
+			ip++
+			constant := c.Constants[c.Code[ip]]
+			sb.WriteString(fmt.Sprintf("IMPORT %v\n", constant))
 		default:
 			sb.WriteString(fmt.Sprintf("UNKNOWN_OP %v\n", c.Code[ip]))
 		}
@@ -132,8 +321,19 @@ func (c *Chunk) String() string {
 	return sb.String()
 }
 
-func (c *Chunk) Write(byte byte) {
+func (c *Chunk) Write(byte byte, line int) {
 	c.Code = append(c.Code, byte)
+	c.Lines = append(c.Lines, line)
+}
+
+// Handler returns the innermost handler covering the instruction at ip.
+func (c *Chunk) Handler(ip int) (Handler, bool) {
+	for _, handler := range c.Handlers {
+		if handler.Start <= ip && ip < handler.End {
+			return handler, true
+		}
+	}
+	return Handler{}, false
 }
 
 func (c *Chunk) AddValue(v Value) byte {
@@ -0,0 +1,44 @@
+package repr
+
+import "fmt"
+
+type Class struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+func NewClass(name string) *Class {
+	return &Class{name, make(map[string]*Closure)}
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	Class  *Class
+	Fields map[string]Value
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{class, make(map[string]Value)}
+}
+
+func (i *Instance) String() string {
+	return fmt.Sprintf("%s instance", i.Class.Name)
+}
+
+// BoundMethod is a method that has been accessed on an instance and
+// remembers that instance as its receiver.
+type BoundMethod struct {
+	Receiver Value
+	Method   *Closure
+}
+
+func NewBoundMethod(receiver Value, method *Closure) *BoundMethod {
+	return &BoundMethod{receiver, method}
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
@@ -0,0 +1,50 @@
+package repr
+
+type Closure struct {
+	Function *Function
+	Upvalues []*Upvalue
+	// Module holds the globals the closure's code refers to.
+	Module *Module
+}
+
+func NewClosure(function *Function, module *Module) *Closure {
+	return &Closure{function, make([]*Upvalue, function.UpvalueCount), module}
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+// Upvalue refers to a variable captured by a closure. While the variable is
+// still on the stack the upvalue points at its slot; once the variable goes
+// out of scope the value is moved into Closed.
+type Upvalue struct {
+	Stack  *[]Value
+	Slot   int
+	Closed Value
+	IsOpen bool
+}
+
+func NewUpvalue(stack *[]Value, slot int) *Upvalue {
+	return &Upvalue{stack, slot, NilVal(), true}
+}
+
+func (u *Upvalue) Get() Value {
+	if u.IsOpen {
+		return (*u.Stack)[u.Slot]
+	}
+	return u.Closed
+}
+
+func (u *Upvalue) Set(value Value) {
+	if u.IsOpen {
+		(*u.Stack)[u.Slot] = value
+	} else {
+		u.Closed = value
+	}
+}
+
+func (u *Upvalue) Close() {
+	u.Closed = (*u.Stack)[u.Slot]
+	u.IsOpen = false
+}
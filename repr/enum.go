@@ -0,0 +1,43 @@
+package repr
+
+import "fmt"
+
+// Enum is a namespace of distinct named values. Its members are only equal
+// to themselves, never to the strings or numbers they are named or numbered
+// after.
+type Enum struct {
+	Name    string
+	Members []*EnumMember
+}
+
+type EnumMember struct {
+	Enum    *Enum
+	Name    string
+	Ordinal int
+}
+
+func NewEnum(name string, memberNames []string) *Enum {
+	enum := &Enum{name, make([]*EnumMember, len(memberNames))}
+	for i, memberName := range memberNames {
+		enum.Members[i] = &EnumMember{enum, memberName, i}
+	}
+	return enum
+}
+
+// Member returns the member of the enum called name.
+func (e *Enum) Member(name string) (*EnumMember, bool) {
+	for _, member := range e.Members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return nil, false
+}
+
+func (e *Enum) String() string {
+	return e.Name
+}
+
+func (m *EnumMember) String() string {
+	return fmt.Sprintf("%s.%s", m.Enum.Name, m.Name)
+}
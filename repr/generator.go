@@ -0,0 +1,17 @@
+package repr
+
+import "fmt"
+
+// Generator is a suspended call to a function containing 'yield'. Resume
+// runs it until its next yield, passing sent as the value of the yield it is
+// suspended at, and reports false once the function has returned instead,
+// along with the value it returned.
+type Generator struct {
+	Name   string
+	Done   bool
+	Resume func(sent Value) (Value, bool)
+}
+
+func (g *Generator) String() string {
+	return fmt.Sprintf("<generator %s>", g.Name)
+}
@@ -0,0 +1,18 @@
+package repr
+
+import "fmt"
+
+// Iterator produces the values a for-in loop runs over, one at a time. Next
+// reports false once there are no values left.
+type Iterator struct {
+	Name string
+	Next func() (Value, bool)
+}
+
+func NewIterator(name string, next func() (Value, bool)) *Iterator {
+	return &Iterator{name, next}
+}
+
+func (i *Iterator) String() string {
+	return fmt.Sprintf("<%s iterator>", i.Name)
+}
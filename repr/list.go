@@ -0,0 +1,36 @@
+package repr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type List struct {
+	Items []Value
+}
+
+func NewList(items []Value) *List {
+	return &List{items}
+}
+
+func (l *List) String() string {
+	sb := strings.Builder{}
+	sb.WriteString("[")
+	for i, item := range l.Items {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(formatElement(item))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// formatElement formats a value nested inside a collection, quoting strings
+// so that ["a, b"] and ["a", "b"] print differently.
+func formatElement(v Value) string {
+	if v.IsString() {
+		return fmt.Sprintf("%q", v.AsString())
+	}
+	return v.String()
+}
@@ -0,0 +1,87 @@
+package repr
+
+import (
+	"math"
+	"strings"
+)
+
+// Map is a hash map from hashable values to values which remembers the
+// order its keys were inserted in.
+type Map struct {
+	Keys    []Value
+	Entries map[Value]Value
+}
+
+func NewMap() *Map {
+	return &Map{[]Value{}, make(map[Value]Value)}
+}
+
+// IsHashable reports whether a value can be used as a map key. Two hashable
+// values refer to the same entry exactly when Equals reports them equal.
+func (v Value) IsHashable() bool {
+	switch v.Type {
+	case VAL_BOOL, VAL_NIL, VAL_STRING, VAL_INT, VAL_ENUM_MEMBER:
+		return true
+	case VAL_NUMBER:
+		return !math.IsNaN(v.AsNumber())
+	default:
+		return false
+	}
+}
+
+// HashKey returns the value used to store v in a map. Floats holding an
+// exact integer are stored as that integer, since the two compare equal.
+func (v Value) HashKey() Value {
+	if i, ok := v.ToInt(); ok && v.IsNumber() {
+		return IntVal(i)
+	}
+	return v
+}
+
+func (m *Map) Get(key Value) (Value, bool) {
+	value, ok := m.Entries[key.HashKey()]
+	return value, ok
+}
+
+func (m *Map) Set(key, value Value) {
+	key = key.HashKey()
+	if _, ok := m.Entries[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Entries[key] = value
+}
+
+func (m *Map) Delete(key Value) bool {
+	key = key.HashKey()
+	if _, ok := m.Entries[key]; !ok {
+		return false
+	}
+
+	delete(m.Entries, key)
+	for i, k := range m.Keys {
+		if k.Equals(key) {
+			m.Keys = append(m.Keys[:i], m.Keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (m *Map) Len() int {
+	return len(m.Keys)
+}
+
+func (m *Map) String() string {
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, key := range m.Keys {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(formatElement(key))
+		sb.WriteString(": ")
+		sb.WriteString(formatElement(m.Entries[key]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
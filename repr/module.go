@@ -0,0 +1,26 @@
+package repr
+
+import "fmt"
+
+// Module is the global namespace of a script. Imported modules expose the
+// globals their script exports.
+type Module struct {
+	Name    string
+	Path    string
+	Globals map[string]Value
+	Exports map[string]bool
+	// Constants holds the names of the globals declared with 'const'.
+	Constants map[string]bool
+}
+
+func NewModule(name, path string, globals map[string]Value, exports []string) *Module {
+	exported := make(map[string]bool)
+	for _, name := range exports {
+		exported[name] = true
+	}
+	return &Module{name, path, globals, exported, make(map[string]bool)}
+}
+
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %s>", m.Name)
+}
@@ -0,0 +1,63 @@
+package repr
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IsNumeric reports whether a value is an integer or a float.
+func (v Value) IsNumeric() bool {
+	return v.Type == VAL_INT || v.Type == VAL_NUMBER
+}
+
+// ToFloat converts an integer or a float to a float64.
+func (v Value) ToFloat() float64 {
+	if v.IsInt() {
+		return float64(v.AsInt())
+	}
+	return v.AsNumber()
+}
+
+// ToInt converts an integer, or a float holding an exact integer that fits
+// in an int64, to an int64.
+func (v Value) ToInt() (int64, bool) {
+	switch v.Type {
+	case VAL_INT:
+		return v.AsInt(), true
+	case VAL_NUMBER:
+		num := v.AsNumber()
+		if math.Trunc(num) != num || num < math.MinInt64 || num >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(num), true
+	default:
+		return 0, false
+	}
+}
+
+// numbersEqual compares two numeric values exactly, so that a large integer
+// never equals a float that merely rounds to the same value.
+func numbersEqual(a, b Value) bool {
+	if a.IsInt() && b.IsInt() {
+		return a.AsInt() == b.AsInt()
+	} else if !a.IsInt() && !b.IsInt() {
+		return a.AsNumber() == b.AsNumber()
+	}
+
+	if a.IsInt() {
+		a, b = b, a
+	}
+	i, ok := a.ToInt()
+	return ok && i == b.AsInt()
+}
+
+// formatFloat prints floats in their shortest exact form, always marked with
+// a decimal point or exponent so they can be told apart from integers.
+func formatFloat(num float64) string {
+	text := strconv.FormatFloat(num, 'g', -1, 64)
+	if math.IsInf(num, 0) || math.IsNaN(num) || strings.ContainsAny(text, ".e") {
+		return text
+	}
+	return text + ".0"
+}
@@ -0,0 +1,44 @@
+package repr
+
+import "strings"
+
+// Tuple is an immutable sequence of values, produced by returning several
+// values from a function and by parenthesised, comma separated expressions.
+type Tuple struct {
+	Items []Value
+}
+
+func NewTuple(items []Value) *Tuple {
+	return &Tuple{items}
+}
+
+func (t *Tuple) String() string {
+	sb := strings.Builder{}
+	sb.WriteString("(")
+	for i, item := range t.Items {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(formatElement(item))
+	}
+	if len(t.Items) == 1 {
+		// Distinguishes a one item tuple from a parenthesised value.
+		sb.WriteString(",")
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// Equals compares tuples element by element, since two tuples holding the
+// same values are indistinguishable.
+func (t *Tuple) Equals(other *Tuple) bool {
+	if len(t.Items) != len(other.Items) {
+		return false
+	}
+	for i, item := range t.Items {
+		if !item.Equals(other.Items[i]) {
+			return false
+		}
+	}
+	return true
+}
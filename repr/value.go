@@ -2,7 +2,7 @@ package repr
 
 import (
 	"fmt"
-	"math"
+	"strconv"
 )
 
 type Type int
@@ -11,15 +11,46 @@ const (
 	VAL_BOOL Type = iota
 	VAL_NIL
 	VAL_NUMBER
+	VAL_INT
 	VAL_STRING
 	VAL_FUNCTION
 	VAL_NATIVE
+	VAL_CLOSURE
+	VAL_CLASS
+	VAL_INSTANCE
+	VAL_BOUND_METHOD
+	VAL_LIST
+	VAL_MAP
+	VAL_MODULE
+	VAL_ITERATOR
+	VAL_GENERATOR
+	VAL_CHANNEL
+	VAL_TUPLE
+	VAL_ENUM
+	VAL_ENUM_MEMBER
+	// VAL_ABSENT fills the slot of a parameter whose argument was omitted. The
+	// function's prologue replaces it with the default value before the body
+	// runs, so it is never visible to Lox code.
+	VAL_ABSENT
 )
 
 type Function struct {
-	Chunk *Chunk
-	Arity int
-	Name  string
+	Chunk        *Chunk
+	Arity        int
+	UpvalueCount int
+	Name         string
+	// Params holds the parameter names, used to resolve named arguments.
+	Params []string
+	// Defaults is the number of trailing parameters with a default value.
+	Defaults int
+	// Variadic functions collect surplus arguments into a list held by their
+	// last parameter.
+	Variadic bool
+	// Generator functions contain 'yield', and return a generator running
+	// their body when called.
+	Generator bool
+	// Exports lists the names a script exports when imported as a module.
+	Exports []string
 }
 
 func (f *Function) String() string {
@@ -45,11 +76,20 @@ type FuncType int
 const (
 	FUNC_FUNCTION FuncType = iota
 	FUNC_SCRIPT
+	FUNC_METHOD
+	FUNC_INITIALIZER
 )
 
 type NativeFn func(argCount int, args []Value) Value
 
-func (_ NativeFn) String() string {
+// Native wraps a NativeFn so that native functions have an identity and can
+// be compared.
+type Native struct {
+	Name string
+	Fn   NativeFn
+}
+
+func (_ *Native) String() string {
 	return "<native fn>"
 }
 
@@ -70,6 +110,10 @@ func NumberVal(value float64) Value {
 	return Value{VAL_NUMBER, value}
 }
 
+func IntVal(value int64) Value {
+	return Value{VAL_INT, value}
+}
+
 func StringVal(value string) Value {
 	return Value{VAL_STRING, value}
 }
@@ -78,10 +122,66 @@ func FunctionVal(value *Function) Value {
 	return Value{VAL_FUNCTION, value}
 }
 
-func NativeVal(value NativeFn) Value {
+func NativeVal(value *Native) Value {
 	return Value{VAL_NATIVE, value}
 }
 
+func ClosureVal(value *Closure) Value {
+	return Value{VAL_CLOSURE, value}
+}
+
+func ClassVal(value *Class) Value {
+	return Value{VAL_CLASS, value}
+}
+
+func InstanceVal(value *Instance) Value {
+	return Value{VAL_INSTANCE, value}
+}
+
+func BoundMethodVal(value *BoundMethod) Value {
+	return Value{VAL_BOUND_METHOD, value}
+}
+
+func ListVal(value *List) Value {
+	return Value{VAL_LIST, value}
+}
+
+func MapVal(value *Map) Value {
+	return Value{VAL_MAP, value}
+}
+
+func ModuleVal(value *Module) Value {
+	return Value{VAL_MODULE, value}
+}
+
+func IteratorVal(value *Iterator) Value {
+	return Value{VAL_ITERATOR, value}
+}
+
+func GeneratorVal(value *Generator) Value {
+	return Value{VAL_GENERATOR, value}
+}
+
+func ChannelVal(value *Channel) Value {
+	return Value{VAL_CHANNEL, value}
+}
+
+func TupleVal(value *Tuple) Value {
+	return Value{VAL_TUPLE, value}
+}
+
+func EnumVal(value *Enum) Value {
+	return Value{VAL_ENUM, value}
+}
+
+func EnumMemberVal(value *EnumMember) Value {
+	return Value{VAL_ENUM_MEMBER, value}
+}
+
+func AbsentVal() Value {
+	return Value{VAL_ABSENT, nil}
+}
+
 func (v Value) AsBool() bool {
 	return v.Data.(bool)
 }
@@ -90,6 +190,10 @@ func (v Value) AsNumber() float64 {
 	return v.Data.(float64)
 }
 
+func (v Value) AsInt() int64 {
+	return v.Data.(int64)
+}
+
 func (v Value) AsString() string {
 	return v.Data.(string)
 }
@@ -98,11 +202,66 @@ func (v Value) AsFunction() *Function {
 	return v.Data.(*Function)
 }
 
-func (v Value) AsNative() NativeFn {
-	return v.Data.(NativeFn)
+func (v Value) AsNative() *Native {
+	return v.Data.(*Native)
+}
+
+func (v Value) AsClosure() *Closure {
+	return v.Data.(*Closure)
+}
+
+func (v Value) AsClass() *Class {
+	return v.Data.(*Class)
+}
+
+func (v Value) AsInstance() *Instance {
+	return v.Data.(*Instance)
+}
+
+func (v Value) AsBoundMethod() *BoundMethod {
+	return v.Data.(*BoundMethod)
+}
+
+func (v Value) AsList() *List {
+	return v.Data.(*List)
+}
+
+func (v Value) AsMap() *Map {
+	return v.Data.(*Map)
+}
+
+func (v Value) AsModule() *Module {
+	return v.Data.(*Module)
+}
+
+func (v Value) AsIterator() *Iterator {
+	return v.Data.(*Iterator)
+}
+
+func (v Value) AsGenerator() *Generator {
+	return v.Data.(*Generator)
+}
+
+func (v Value) AsChannel() *Channel {
+	return v.Data.(*Channel)
+}
+
+func (v Value) AsTuple() *Tuple {
+	return v.Data.(*Tuple)
+}
+
+func (v Value) AsEnum() *Enum {
+	return v.Data.(*Enum)
+}
+
+func (v Value) AsEnumMember() *EnumMember {
+	return v.Data.(*EnumMember)
 }
 
 func (v Value) Equals(v2 Value) bool {
+	if v.IsNumeric() && v2.IsNumeric() {
+		return numbersEqual(v, v2)
+	}
 	if v.Type != v2.Type {
 		return false
 	}
@@ -112,15 +271,39 @@ func (v Value) Equals(v2 Value) bool {
 		return v.AsBool() == v2.AsBool()
 	case VAL_NIL:
 		return true
-	case VAL_NUMBER:
-		return v.AsNumber() == v2.AsNumber()
 	case VAL_STRING:
 		return v.AsString() == v2.AsString()
 	case VAL_FUNCTION:
-		return v.AsFunction().Chunk == v.AsFunction().Chunk
+		return v.AsFunction() == v2.AsFunction()
 	case VAL_NATIVE:
-		// TODO == NativeFn
-		return false
+		return v.AsNative() == v2.AsNative()
+	case VAL_CLOSURE:
+		return v.AsClosure() == v2.AsClosure()
+	case VAL_CLASS:
+		return v.AsClass() == v2.AsClass()
+	case VAL_INSTANCE:
+		return v.AsInstance() == v2.AsInstance()
+	case VAL_BOUND_METHOD:
+		a, b := v.AsBoundMethod(), v2.AsBoundMethod()
+		return a.Receiver.Equals(b.Receiver) && a.Method == b.Method
+	case VAL_LIST:
+		return v.AsList() == v2.AsList()
+	case VAL_MAP:
+		return v.AsMap() == v2.AsMap()
+	case VAL_MODULE:
+		return v.AsModule() == v2.AsModule()
+	case VAL_ITERATOR:
+		return v.AsIterator() == v2.AsIterator()
+	case VAL_GENERATOR:
+		return v.AsGenerator() == v2.AsGenerator()
+	case VAL_CHANNEL:
+		return v.AsChannel() == v2.AsChannel()
+	case VAL_TUPLE:
+		return v.AsTuple().Equals(v2.AsTuple())
+	case VAL_ENUM:
+		return v.AsEnum() == v2.AsEnum()
+	case VAL_ENUM_MEMBER:
+		return v.AsEnumMember() == v2.AsEnumMember()
 	default:
 		// Not reachable
 		return false
@@ -139,6 +322,10 @@ func (v Value) IsNumber() bool {
 	return v.Type == VAL_NUMBER
 }
 
+func (v Value) IsInt() bool {
+	return v.Type == VAL_INT
+}
+
 func (v Value) IsString() bool {
 	return v.Type == VAL_STRING
 }
@@ -151,6 +338,62 @@ func (v Value) IsNative() bool {
 	return v.Type == VAL_NATIVE
 }
 
+func (v Value) IsClosure() bool {
+	return v.Type == VAL_CLOSURE
+}
+
+func (v Value) IsClass() bool {
+	return v.Type == VAL_CLASS
+}
+
+func (v Value) IsInstance() bool {
+	return v.Type == VAL_INSTANCE
+}
+
+func (v Value) IsBoundMethod() bool {
+	return v.Type == VAL_BOUND_METHOD
+}
+
+func (v Value) IsList() bool {
+	return v.Type == VAL_LIST
+}
+
+func (v Value) IsMap() bool {
+	return v.Type == VAL_MAP
+}
+
+func (v Value) IsModule() bool {
+	return v.Type == VAL_MODULE
+}
+
+func (v Value) IsIterator() bool {
+	return v.Type == VAL_ITERATOR
+}
+
+func (v Value) IsGenerator() bool {
+	return v.Type == VAL_GENERATOR
+}
+
+func (v Value) IsChannel() bool {
+	return v.Type == VAL_CHANNEL
+}
+
+func (v Value) IsTuple() bool {
+	return v.Type == VAL_TUPLE
+}
+
+func (v Value) IsEnum() bool {
+	return v.Type == VAL_ENUM
+}
+
+func (v Value) IsEnumMember() bool {
+	return v.Type == VAL_ENUM_MEMBER
+}
+
+func (v Value) IsAbsent() bool {
+	return v.Type == VAL_ABSENT
+}
+
 func (v Value) String() string {
 	switch v.Type {
 	case VAL_BOOL:
@@ -158,11 +401,9 @@ func (v Value) String() string {
 	case VAL_NIL:
 		return fmt.Sprintf("nil")
 	case VAL_NUMBER:
-		num := v.Data.(float64)
-		if math.Mod(num, 1.0) == 0 {
-			return fmt.Sprintf("%.0f", num)
-		}
-		return fmt.Sprintf("%f", v.Data.(float64))
+		return formatFloat(v.AsNumber())
+	case VAL_INT:
+		return strconv.FormatInt(v.AsInt(), 10)
 	case VAL_STRING:
 		return fmt.Sprintf("%s", v.Data.(string))
 	case VAL_FUNCTION:
@@ -174,6 +415,32 @@ func (v Value) String() string {
 		}
 	case VAL_NATIVE:
 		return "<native fn>"
+	case VAL_CLOSURE:
+		return v.AsClosure().String()
+	case VAL_CLASS:
+		return v.AsClass().String()
+	case VAL_INSTANCE:
+		return v.AsInstance().String()
+	case VAL_BOUND_METHOD:
+		return v.AsBoundMethod().String()
+	case VAL_LIST:
+		return v.AsList().String()
+	case VAL_MAP:
+		return v.AsMap().String()
+	case VAL_MODULE:
+		return v.AsModule().String()
+	case VAL_ITERATOR:
+		return v.AsIterator().String()
+	case VAL_GENERATOR:
+		return v.AsGenerator().String()
+	case VAL_CHANNEL:
+		return v.AsChannel().String()
+	case VAL_TUPLE:
+		return v.AsTuple().String()
+	case VAL_ENUM:
+		return v.AsEnum().String()
+	case VAL_ENUM_MEMBER:
+		return v.AsEnumMember().String()
 	default:
 		return fmt.Sprintf("%v", v.Data)
 	}
@@ -3,20 +3,36 @@ package scanner
 import "golox/token"
 
 var keywords = map[string]token.Type{
-	"and":    token.AND,
-	"class":  token.CLASS,
-	"else":   token.ELSE,
-	"false":  token.FALSE,
-	"for":    token.FOR,
-	"fun":    token.FUN,
-	"if":     token.IF,
-	"nil":    token.NIL,
-	"or":     token.OR,
-	"print":  token.PRINT,
-	"return": token.RETURN,
-	"super":  token.SUPER,
-	"this":   token.THIS,
-	"true":   token.TRUE,
-	"var":    token.VAR,
-	"while":  token.WHILE,
+	"and":      token.AND,
+	"assert":   token.ASSERT,
+	"break":    token.BREAK,
+	"case":     token.CASE,
+	"catch":    token.CATCH,
+	"class":    token.CLASS,
+	"const":    token.CONST,
+	"continue": token.CONTINUE,
+	"else":     token.ELSE,
+	"enum":     token.ENUM,
+	"export":   token.EXPORT,
+	"false":    token.FALSE,
+	"finally":  token.FINALLY,
+	"for":      token.FOR,
+	"fun":      token.FUN,
+	"if":       token.IF,
+	"import":   token.IMPORT,
+	"in":       token.IN,
+	"match":    token.MATCH,
+	"nil":      token.NIL,
+	"or":       token.OR,
+	"print":    token.PRINT,
+	"return":   token.RETURN,
+	"spawn":    token.SPAWN,
+	"super":    token.SUPER,
+	"this":     token.THIS,
+	"throw":    token.THROW,
+	"true":     token.TRUE,
+	"try":      token.TRY,
+	"var":      token.VAR,
+	"while":    token.WHILE,
+	"yield":    token.YIELD,
 }
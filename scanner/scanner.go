@@ -6,18 +6,25 @@ import (
 	"golox/token"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Scanner struct {
-	Source  string
-	Tokens  []token.Token
+	Source string
+	Tokens []token.Token
+	// Offsets holds the byte offset in Source at which each token starts.
+	Offsets []int
 	Start   int
 	Current int
 	Line    int
+	// Interpolations holds, for each string interpolation being scanned,
+	// the number of unclosed braces inside its expression.
+	Interpolations []int
 }
 
 func New(source string) *Scanner {
-	return &Scanner{source, []token.Token{}, 0, 0, 1}
+	return &Scanner{source, []token.Token{}, []int{}, 0, 0, 1, []int{}}
 }
 
 func (sc *Scanner) ScanTokens() []token.Token {
@@ -26,7 +33,12 @@ func (sc *Scanner) ScanTokens() []token.Token {
 		sc.scanToken()
 	}
 
+	if len(sc.Interpolations) > 0 {
+		loxerror.Error(sc.Line, "Unterminated string interpolation.")
+	}
+
 	sc.Tokens = append(sc.Tokens, token.Token{token.EOF, "", nil, sc.Line})
+	sc.Offsets = append(sc.Offsets, len(sc.Source))
 	return sc.Tokens
 }
 
@@ -42,48 +54,55 @@ func (sc *Scanner) isAtEnd() bool {
 	return sc.Current >= len(sc.Source)
 }
 
-func (sc *Scanner) advance() byte {
-	sc.Current++
-	return sc.Source[sc.Current-1]
+// advance consumes and returns the next rune. Positions in the scanner are
+// byte offsets into Source, so lexemes can be sliced out of it directly.
+func (sc *Scanner) advance() rune {
+	r, size := utf8.DecodeRuneInString(sc.Source[sc.Current:])
+	sc.Current += size
+	return r
 }
 
-func (sc *Scanner) match(expected byte) bool {
+func (sc *Scanner) match(expected rune) bool {
 	if sc.isAtEnd() {
 		return false
 	}
-	if sc.Source[sc.Current] != expected {
+	if sc.peek() != expected {
 		return false
 	}
-	sc.Current++
+	sc.advance()
 	return true
 }
 
-func (sc *Scanner) peek() byte {
+func (sc *Scanner) peek() rune {
 	if sc.isAtEnd() {
 		return 0
 	}
-	return sc.Source[sc.Current]
+	r, _ := utf8.DecodeRuneInString(sc.Source[sc.Current:])
+	return r
 }
 
-func (sc *Scanner) peekNext() byte {
-	if sc.Current+1 >= len(sc.Source) {
+func (sc *Scanner) peekNext() rune {
+	if sc.isAtEnd() {
 		return 0
 	}
-	return sc.Source[sc.Current+1]
+	_, size := utf8.DecodeRuneInString(sc.Source[sc.Current:])
+	if sc.Current+size >= len(sc.Source) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(sc.Source[sc.Current+size:])
+	return r
 }
 
-func (sc *Scanner) isAlpha(c byte) bool {
-	return c >= 'a' && c <= 'z' ||
-		c >= 'A' && c <= 'Z' ||
-		c == '_'
+func (sc *Scanner) isAlpha(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
 }
 
-func (sc *Scanner) isDigit(c byte) bool {
+func (sc *Scanner) isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
-func (sc *Scanner) isAlphanumeric(c byte) bool {
-	return sc.isAlpha(c) || sc.isDigit(c)
+func (sc *Scanner) isAlphanumeric(c rune) bool {
+	return sc.isAlpha(c) || unicode.IsDigit(c)
 }
 
 func (sc *Scanner) handleIdentifier() {
@@ -112,19 +131,40 @@ func (sc *Scanner) handleNumber() {
 		for sc.isDigit(sc.peek()) {
 			sc.advance()
 		}
-	}
 
-	number, _ := strconv.ParseFloat(sc.Source[sc.Start:sc.Current], 64)
+		number, _ := strconv.ParseFloat(sc.Source[sc.Start:sc.Current], 64)
+		sc.addToken(token.NUMBER, number)
+		return
+	}
 
+	// Literals without a fractional part are integers.
+	number, err := strconv.ParseInt(sc.Source[sc.Start:sc.Current], 10, 64)
+	if err != nil {
+		loxerror.Error(sc.Line, "Integer literal is too large.")
+	}
 	sc.addToken(token.NUMBER, number)
 }
 
+// handleString scans the rest of a string literal. A "${" inside the literal
+// ends the current segment with an INTERPOLATION token; scanning of the
+// literal resumes at the '}' closing the embedded expression.
 func (sc *Scanner) handleString() {
+	sb := strings.Builder{}
 	for sc.peek() != '"' && !sc.isAtEnd() {
-		if sc.peek() == '\n' {
+		c := sc.advance()
+		if c == '\n' {
 			sc.Line++
 		}
-		sc.advance()
+
+		if c == '\\' {
+			sc.handleEscape(&sb)
+		} else if c == '$' && sc.match('{') {
+			sc.Interpolations = append(sc.Interpolations, 0)
+			sc.addToken(token.INTERPOLATION, sb.String())
+			return
+		} else {
+			sb.WriteRune(c)
+		}
 	}
 
 	if sc.isAtEnd() {
@@ -135,13 +175,64 @@ func (sc *Scanner) handleString() {
 	// For the closing ".
 	sc.advance()
 
-	value := sc.Source[sc.Start+1 : sc.Current-1]
-	sc.addToken(token.STRING, value)
+	sc.addToken(token.STRING, sb.String())
+}
+
+// handleEscape decodes the escape sequence following a backslash in a string
+// literal and writes the character it stands for.
+func (sc *Scanner) handleEscape(sb *strings.Builder) {
+	if sc.isAtEnd() {
+		return
+	}
+
+	c := sc.advance()
+	switch c {
+	case 'n':
+		sb.WriteRune('\n')
+	case 't':
+		sb.WriteRune('\t')
+	case 'r':
+		sb.WriteRune('\r')
+	case '0':
+		sb.WriteRune(0)
+	case '"':
+		sb.WriteRune('"')
+	case '$':
+		sb.WriteRune('$')
+	case '\\':
+		sb.WriteRune('\\')
+	case 'u':
+		// \u{XXXX} with one to six hex digits naming a code point.
+		if !sc.match('{') {
+			loxerror.Error(sc.Line, "Expect '{' after '\\u'.")
+			return
+		}
+
+		start := sc.Current
+		for sc.peek() != '}' && !sc.isAtEnd() && sc.peek() != '"' {
+			sc.advance()
+		}
+		digits := sc.Source[start:sc.Current]
+		if !sc.match('}') {
+			loxerror.Error(sc.Line, "Unterminated unicode escape sequence.")
+			return
+		}
+
+		codePoint, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(codePoint)) {
+			loxerror.Error(sc.Line, fmt.Sprintf("Invalid unicode escape sequence '\\u{%s}'.", digits))
+			return
+		}
+		sb.WriteRune(rune(codePoint))
+	default:
+		loxerror.Error(sc.Line, fmt.Sprintf("Invalid escape sequence '\\%c'.", c))
+	}
 }
 
 func (sc *Scanner) addToken(tokenType token.Type, literal interface{}) {
 	text := sc.Source[sc.Start:sc.Current]
 	sc.Tokens = append(sc.Tokens, token.Token{tokenType, text, literal, sc.Line})
+	sc.Offsets = append(sc.Offsets, sc.Start)
 }
 
 func (sc *Scanner) scanToken() {
@@ -152,21 +243,65 @@ func (sc *Scanner) scanToken() {
 	case ')':
 		sc.addToken(token.RIGHT_PAREN, nil)
 	case '{':
+		if len(sc.Interpolations) > 0 {
+			sc.Interpolations[len(sc.Interpolations)-1]++
+		}
 		sc.addToken(token.LEFT_BRACE, nil)
 	case '}':
+		if len(sc.Interpolations) > 0 {
+			last := len(sc.Interpolations) - 1
+			if sc.Interpolations[last] == 0 {
+				sc.Interpolations = sc.Interpolations[:last]
+				sc.handleString()
+				return
+			}
+			sc.Interpolations[last]--
+		}
 		sc.addToken(token.RIGHT_BRACE, nil)
+	case '[':
+		sc.addToken(token.LEFT_BRACKET, nil)
+	case ']':
+		sc.addToken(token.RIGHT_BRACKET, nil)
+	case ':':
+		sc.addToken(token.COLON, nil)
+	case '?':
+		sc.addToken(token.QUESTION, nil)
 	case ',':
 		sc.addToken(token.COMMA, nil)
 	case '.':
-		sc.addToken(token.DOT, nil)
+		if sc.peek() == '.' && sc.peekNext() == '.' {
+			sc.advance()
+			sc.advance()
+			sc.addToken(token.ELLIPSIS, nil)
+		} else {
+			sc.addToken(token.DOT, nil)
+		}
 	case '-':
-		sc.addToken(token.MINUS, nil)
+		if sc.match('-') {
+			sc.addToken(token.MINUS_MINUS, nil)
+		} else if sc.match('=') {
+			sc.addToken(token.MINUS_EQUAL, nil)
+		} else {
+			sc.addToken(token.MINUS, nil)
+		}
 	case '+':
-		sc.addToken(token.PLUS, nil)
+		if sc.match('+') {
+			sc.addToken(token.PLUS_PLUS, nil)
+		} else if sc.match('=') {
+			sc.addToken(token.PLUS_EQUAL, nil)
+		} else {
+			sc.addToken(token.PLUS, nil)
+		}
 	case ';':
 		sc.addToken(token.SEMICOLON, nil)
 	case '*':
-		sc.addToken(token.STAR, nil)
+		if sc.match('*') {
+			sc.addToken(token.STAR_STAR, nil)
+		} else if sc.match('=') {
+			sc.addToken(token.STAR_EQUAL, nil)
+		} else {
+			sc.addToken(token.STAR, nil)
+		}
 	case '!':
 		if sc.match('=') {
 			sc.addToken(token.BANG_EQUAL, nil)
@@ -176,21 +311,39 @@ func (sc *Scanner) scanToken() {
 	case '=':
 		if sc.match('=') {
 			sc.addToken(token.EQUAL_EQUAL, nil)
+		} else if sc.match('>') {
+			sc.addToken(token.ARROW, nil)
 		} else {
 			sc.addToken(token.EQUAL, nil)
 		}
 	case '<':
 		if sc.match('=') {
 			sc.addToken(token.LESS_EQUAL, nil)
+		} else if sc.match('<') {
+			sc.addToken(token.LESS_LESS, nil)
 		} else {
 			sc.addToken(token.LESS, nil)
 		}
 	case '>':
 		if sc.match('=') {
 			sc.addToken(token.GREATER_EQUAL, nil)
+		} else if sc.match('>') {
+			sc.addToken(token.GREATER_GREATER, nil)
 		} else {
 			sc.addToken(token.GREATER, nil)
 		}
+	case '&':
+		sc.addToken(token.AMPERSAND, nil)
+	case '|':
+		sc.addToken(token.PIPE, nil)
+	case '^':
+		sc.addToken(token.CARET, nil)
+	case '~':
+		if sc.match('/') {
+			sc.addToken(token.TILDE_SLASH, nil)
+		} else {
+			sc.addToken(token.TILDE, nil)
+		}
 	case '/':
 		// Handle line comments
 		if sc.match('/') {
@@ -199,7 +352,7 @@ func (sc *Scanner) scanToken() {
 			}
 			// Handle block comments
 		} else if sc.match('*') {
-			for sc.peek() != '*' && sc.peekNext() != '/' && !sc.isAtEnd() {
+			for !(sc.peek() == '*' && sc.peekNext() == '/') && !sc.isAtEnd() {
 				if sc.peek() == '\n' {
 					sc.Line++
 				}
@@ -207,10 +360,22 @@ func (sc *Scanner) scanToken() {
 			}
 			if sc.isAtEnd() {
 				loxerror.Error(sc.Line, "Unterminated block comment.")
+			} else {
+				// Consume the closing */.
+				sc.advance()
+				sc.advance()
 			}
+		} else if sc.match('=') {
+			sc.addToken(token.SLASH_EQUAL, nil)
 		} else {
 			sc.addToken(token.SLASH, nil)
 		}
+	case '%':
+		if sc.match('=') {
+			sc.addToken(token.PERCENT_EQUAL, nil)
+		} else {
+			sc.addToken(token.PERCENT, nil)
+		}
 	case ' ':
 	case '\r':
 	case '\t':
@@ -0,0 +1,52 @@
+package tests
+
+import "testing"
+
+func TestClass(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`class Foo {} var foo = Foo(); foo.bar = "baz"; print foo.bar;`, "baz"},
+		{`class Foo {} var foo = Foo(); print foo.bar = "baz";`, "baz"},
+		{`class Foo { bar() { return "baz"; } } print Foo().bar();`, "baz"},
+		{`class Foo { bar() { return "baz"; } } var method = Foo().bar; print method();`, "baz"},
+		{`class Foo { init(name) { this.name = name; } } print Foo("bar").name;`, "bar"},
+		{`class Foo { init(name) { this.name = name; } getName() { return this.name; } }
+				 print Foo("bar").getName();`, "bar"},
+		{`class Foo { init() { this.x = 1; return; } } var foo = Foo(); print foo.init().x;`, int64(1)},
+		{`class Counter { init() { this.count = 0; } inc() { this.count = this.count + 1; return this; } }
+				 print Counter().inc().inc().inc().count;`, int64(3)},
+		{`class Foo { bar() { fun inner() { return this.x; } return inner; } }
+				 var foo = Foo(); foo.x = "captured"; print foo.bar()();`, "captured"},
+		{`class Foo {} fun bar() { return "field"; } var foo = Foo(); foo.bar = bar; print foo.bar();`, "field"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestInheritance(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`class A { method() { return "A"; } } class B < A {} print B().method();`, "A"},
+		{`class A { method() { return "A"; } } class B < A { method() { return "B"; } } print B().method();`, "B"},
+		{`class A { method() { return "A"; } } class B < A { method() { return "B" + super.method(); } }
+				 print B().method();`, "BA"},
+		{`class A { method() { return "A"; } } class B < A { method() { var m = super.method; return m(); } }
+				 print B().method();`, "A"},
+		{`class A { init(x) { this.x = x; } } class B < A { init(x, y) { super.init(x); this.y = y; } }
+				 var b = B(1, 2); print b.x + b.y;`, int64(3)},
+		{`class A { say() { return "A"; } } class B < A { say() { return "B"; } test() { return super.say(); } }
+				 class C < B {} print C().test();`, "A"},
+		{`class A { name() { return "A"; } greet() { return "hi " + this.name(); } }
+				 class B < A { name() { return "B"; } } print B().greet();`, "hi B"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"golox/vm"
+	"testing"
+)
+
+func TestConst(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`const a = 1; print a + 1;`, int64(2)},
+		{`{ const a = "local"; print a; }`, "local"},
+		{`const a = 1; { var a = 2; a = 3; print a; }`, int64(3)},
+		{`fun f() { const n = 10; return () => n * 2; } print f()();`, int64(20)},
+		{`const items = [1]; items[0] = 2; print items[0];`, int64(2)},
+		{`fun set() { limit = 2; } const limit = 1; try { set(); } catch (e) { print e.message; }`, "Cannot assign to constant 'limit'."},
+		{`fun set() { limit += 1; } const limit = 1; try { set(); } catch (e) { print limit; }`, int64(1)},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestConstAcrossChunks(t *testing.T) {
+	tests := []struct {
+		sources []string
+		result  interface{}
+	}{
+		{[]string{`const a = 1;`, `print a;`}, int64(1)},
+		{[]string{`const a = 1;`, `try { a = 2; } catch (e) { print e.message; }`}, "Cannot assign to constant 'a'."},
+		{[]string{`const a = 1;`, `var a = 2;`, `print a;`}, int64(1)},
+		{[]string{`const a = 1;`, `try { a++; } catch (e) { print a; }`}, int64(1)},
+	}
+
+	for _, test := range tests {
+		vmachine := vm.New()
+		for _, source := range test.sources {
+			vmachine.Interpret(source)
+		}
+		if vmachine.Out != test.result {
+			t.Errorf("Incorrect result for sources %q. Expected: %v. Got: %v.", test.sources, test.result, vmachine.Out)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package tests
+
+import "testing"
+
+func TestEnum(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`enum Color { Red, Green, Blue } print "${Color.Red}";`, "Color.Red"},
+		{`enum Color { Red, Green, Blue, } print "${Color}";`, "Color"},
+		{`enum Color { Red, Green, Blue } print Color.Blue.ordinal;`, int64(2)},
+		{`enum Color { Red, Green, Blue } print Color.Green.name;`, "Green"},
+		{`enum Color { Red, Green, Blue } var c = Color.Red; print c == Color.Red;`, true},
+		{`enum Color { Red, Green, Blue } print Color.Red == Color.Green;`, false},
+		{`enum Color { Red, Green, Blue } print Color.Red == "Red";`, false},
+		{`enum Color { Red, Green, Blue } print Color.Red == 0;`, false},
+		{`enum A { X } enum B { X } print A.X == B.X;`, false},
+		{`enum Color { Red, Green, Blue } print Color[1] == Color.Green;`, true},
+		{`enum Color { Red, Green, Blue } print len(Color);`, int64(3)},
+		{`enum Color { Red, Green, Blue } var s = ""; for (var c in Color) s += c.name; print s;`, "RedGreenBlue"},
+		{`enum Color { Red, Green, Blue } var m = {Color.Red: 1, "Red": 2}; print m[Color.Red];`, int64(1)},
+		{`enum Color { Red, Green, Blue } print "${[Color.Red, Color.Blue]}";`, "[Color.Red, Color.Blue]"},
+		{`{ enum Dir { Up, Down } fun f() { return Dir.Down; } print "${f()}"; }`, "Dir.Down"},
+		{`enum Color { Red } try { print Color.Purple; } catch (e) { print e.message; }`, "Enum 'Color' has no member 'Purple'."},
+		{`enum Color { Red } try { print Color.Red.foo; } catch (e) { print e.message; }`, "Undefined property 'foo'."},
+		{`enum Empty {} print len(Empty);`, int64(0)},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
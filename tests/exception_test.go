@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"golox/vm"
+	"testing"
+)
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`try { throw "boom"; } catch (e) { print e; }`, "boom"},
+		{`try { throw 42; } catch (e) { print e + 1; }`, int64(43)},
+		{`try { print "ok"; } catch (e) { print e; }`, "ok"},
+		{`var a = 1; try { var b = 2; throw b; } catch (e) { print a + e; }`, int64(3)},
+		{`fun f() { throw "deep"; } fun g() { f(); } try { g(); } catch (e) { print e; }`, "deep"},
+		{`try { try { throw "a"; } catch (e) { throw e + "b"; } } catch (e) { print e; }`, "ab"},
+		{`fun f() { try { throw "x"; } catch (e) { return "caught " + e; } } print f();`, "caught x"},
+		{`var n = 0; for (var i = 0; i < 5; i++) { try { if (i % 2 == 0) throw i; } catch (e) { n += e; } } print n;`, int64(6)},
+		{`try { sort([2, 1], (a, b) => nil + 1); } catch (e) { print e.message; }`, "Operands must be numbers."},
+		{`fun f() { var x = "captured"; try { throw () => x; } catch (g) { return g(); } } print f();`, "captured"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestFinally(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var s = ""; try { s += "t"; } finally { s += "f"; } print s;`, "tf"},
+		{`var s = ""; try { try { throw 1; } finally { s += "f"; } } catch (e) { s += "c"; } print s;`, "fc"},
+		{`var s = ""; try { s += "t"; } catch (e) { s += "c"; } finally { s += "f"; } print s;`, "tf"},
+		{`var s = ""; try { throw 1; } catch (e) { s += "c"; } finally { s += "f"; } print s;`, "cf"},
+		{`var s = ""; try { try { throw 1; } catch (e) { throw 2; } finally { s += "f"; } } catch (e) { s += "${e}"; } print s;`, "f2"},
+		{`var s = ""; fun f() { try { return "r"; } finally { s += "f"; } } var r = f(); print s + r;`, "fr"},
+		{`fun f() { try { return 1; } finally { return 2; } } print f();`, int64(2)},
+		{`var x = "outer"; fun f() { try { var x = "inner"; return x; } finally { print x; } } f();`, "outer"},
+		{`var s = ""; for (var i = 0; i < 3; i++) { try { if (i == 1) continue; s += "${i}"; } finally { s += "f"; } } print s;`, "0ff2f"},
+		{`var s = ""; while (true) { try { break; } finally { s += "f"; } } print s;`, "f"},
+		{`var s = ""; fun f() { try { try { return 1; } finally { s += "a"; } } finally { s += "b"; } } f(); print s;`, "ab"},
+		{`var s = ""; fun f() { try { try { return 1; } finally { throw "x"; } } catch (e) { s += e; } return 2; } var r = f(); print s + "${r}";`, "x2"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`try { print undefined; } catch (e) { print e.message; }`, "Undefined variable 'undefined'."},
+		{`try { 1 + "a"; } catch (e) { print e.message; }`, "Operands must be numbers."},
+		{`try { [1, 2][2]; } catch (e) { print e.message; }`, "Index out of range."},
+		{`try { 1 ~/ 0; } catch (e) { print e.message; }`, "Division by zero."},
+		{`fun f(a) {} try { f(); } catch (e) { print e.message; }`, "Expected 1 arguments but got 0."},
+		{`try { len(1); } catch (e) { print e.message; }`, "len() expects a list, a tuple, a map, a string or an enum."},
+		{`try { nil.x; } catch (e) { print e.message; }`, "Only instances have properties."},
+		{"fun f() {\n\n  return nil + 1;\n}\ntry { f(); } catch (e) { print e.stack; }", "[line 3] in f()\n[line 5] in script"},
+		{`class MyError < Error {} try { throw MyError("mine"); } catch (e) { print e.message; }`, "mine"},
+		{`try { throw Error("made"); } catch (e) { print e.stack; }`, "[line 1] in script"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestUncaughtException(t *testing.T) {
+	sources := []string{
+		`throw "uncaught";`,
+		`fun f() { return 1 + nil; } f();`,
+		`try { throw 1; } finally {}`,
+	}
+
+	for _, source := range sources {
+		if result := vm.New().Interpret(source); result != vm.INTERPRET_RUNTIME_ERROR {
+			t.Errorf("Expected a runtime error for source '%s'. Got: %v.", source, result)
+		}
+	}
+}
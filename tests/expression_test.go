@@ -19,9 +19,9 @@ func TestUnaryOp(t *testing.T) {
 		source string
 		result interface{}
 	}{
-		{"-3", -3.0},
-		{"--3", 3.0},
-		{"-(3 + 5)", -8.0},
+		{"-3", int64(-3)},
+		{"--3", int64(3)},
+		{"-(3 + 5)", int64(-8)},
 		{"!true", false},
 		{"!!false", false},
 		{"!nil", true},
@@ -37,13 +37,13 @@ func TestBinaryOp(t *testing.T) {
 		source string
 		result interface{}
 	}{
-		{"3 + 4", 7.0},
-		{"3 - 4", -1.0},
-		{"3 * 4", 12.0},
+		{"3 + 4", int64(7)},
+		{"3 - 4", int64(-1)},
+		{"3 * 4", int64(12)},
 		{"3 / 4", 0.75},
 		{"3 + 6 / 2", 6.0},
-		{"3 * (3 + 1)", 12.0},
-		{"(5 - (3 - 1)) + -1", 2.0},
+		{"3 * (3 + 1)", int64(12)},
+		{"(5 - (3 - 1)) + -1", int64(2)},
 		{"3 == 4", false},
 		{"3 != 4", true},
 		{"3 > 4", false},
@@ -59,3 +59,131 @@ func TestBinaryOp(t *testing.T) {
 		RunExpressionTest(t, test.source, test.result)
 	}
 }
+
+func TestInterpolation(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`"Hello ${"World"}!"`, "Hello World!"},
+		{`"${1 + 2}"`, "3"},
+		{`"a${1}b${2}c"`, "a1b2c"},
+		{`"${nil} ${true} ${[1, "x"]}"`, `nil true [1, "x"]`},
+		{`"outer ${"inner ${1 + 1}"}"`, "outer inner 2"},
+		{`"${ {"k": "v"}["k"] }"`, "v"},
+		{`"cost: \$${5}"`, "cost: $5"},
+		{`"${"a"}${"b"}"`, "ab"},
+	}
+
+	for _, test := range tests {
+		RunExpressionTest(t, test.source, test.result)
+	}
+}
+
+func TestArithmeticOp(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{"7 % 3", int64(1)},
+		{"-7 % 3", int64(2)},
+		{"7 % -3", int64(-2)},
+		{"-7 % -3", int64(-1)},
+		{"5.5 % 2", 1.5},
+		{"2 ** 10", int64(1024)},
+		{"2 ** 3 ** 2", int64(512)},
+		{"-2 ** 2", int64(-4)},
+		{"2 ** -1", 0.5},
+		{"2 * 3 ** 2", int64(18)},
+		{"7 ~/ 2", int64(3)},
+		{"-7 ~/ 2", int64(-4)},
+		{"7.5 ~/ 2", 3.0},
+		{"1 + 7 ~/ 2 * 2", int64(7)},
+		{"(7) ~/ 2", int64(3)},
+	}
+
+	for _, test := range tests {
+		RunExpressionTest(t, test.source, test.result)
+	}
+}
+
+func TestBitwiseOp(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{"12 & 10", int64(8)},
+		{"12 | 10", int64(14)},
+		{"12 ^ 10", int64(6)},
+		{"~5", int64(-6)},
+		{"~~5", int64(5)},
+		{"1 << 4", int64(16)},
+		{"256 >> 4", int64(16)},
+		{"-16 >> 2", int64(-4)},
+		{"1 << 64", int64(0)},
+		{"6 & 3 == 2", true},
+		{"1 | 2 ^ 3 & 4", int64(3)},
+		{"1 + 1 << 2", int64(8)},
+		{"5.0 & 4", int64(4)},
+	}
+
+	for _, test := range tests {
+		RunExpressionTest(t, test.source, test.result)
+	}
+}
+
+func TestIntegerOp(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{"1", int64(1)},
+		{"1.0", 1.0},
+		{"1 + 2.5", 3.5},
+		{"2 * 1.5", 3.0},
+		{"6 / 3", 2.0},
+		{"9007199254740993 + 1", int64(9007199254740994)},
+		{"9223372036854775807 - 1", int64(9223372036854775806)},
+		{"1 == 1.0", true},
+		{"9007199254740993 == 9007199254740992.0", false},
+		{"2 < 2.5", true},
+		{"2 ** 62", int64(4611686018427387904)},
+		{"2 ** 0.5 > 1.41", true},
+		{"int(3.9)", int64(3)},
+		{"int(-3.9)", int64(-3)},
+		{`int("42")`, int64(42)},
+		{"float(3)", 3.0},
+		{`float("2.5")`, 2.5},
+		{`"${1.0} ${2} ${0.1 + 0.2} ${1 / 4}"`, "1.0 2 0.30000000000000004 0.25"},
+		{`{1: "int"}[1.0]`, "int"},
+		{`[1, 2, 3][1.0]`, int64(2)},
+	}
+
+	for _, test := range tests {
+		RunExpressionTest(t, test.source, test.result)
+	}
+}
+
+func TestTernary(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{"true ? 1 : 2", int64(1)},
+		{"false ? 1 : 2", int64(2)},
+		{"nil ? 1 : 2", int64(2)},
+		{"1 < 2 ? \"yes\" : \"no\"", "yes"},
+		{"false ? 1 : true ? 2 : 3", int64(2)},
+		{"false ? 1 : false ? 2 : 3", int64(3)},
+		{"true ? false ? 1 : 2 : 3", int64(2)},
+		{"false or true ? 1 : 2", int64(1)},
+		{"1 + (true ? 1 : 2) * 3", int64(4)},
+		{"[true ? 1 : 2, false ? 3 : 4][1]", int64(4)},
+		{`{"a": true ? 1 : 2}["a"]`, int64(1)},
+		{"[1, 2, 3][true ? 1 : 0:][0]", int64(2)},
+	}
+
+	for _, test := range tests {
+		RunExpressionTest(t, test.source, test.result)
+	}
+}
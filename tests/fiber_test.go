@@ -0,0 +1,60 @@
+package tests
+
+import "testing"
+
+func TestSpawn(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var c = channel(); fun f(x) { send(c, x * 2); } spawn f(21); print receive(c);`, int64(42)},
+		{`var c = channel(); fun f() { for (var i in range(3)) send(c, i); close(c); } spawn f(); var s = 0; var v = receive(c); while (v != nil) { s += v; v = receive(c); } print s;`, int64(3)},
+		{`var c = channel(); class A { init(n) { this.n = n; } run() { send(c, this.n); } } spawn A(5).run(); print receive(c);`, int64(5)},
+		{`fun main() { var n = 0; var done = channel(); fun inc() { n += 1; send(done, nil); } spawn inc(); spawn inc(); receive(done); receive(done); print n; } main();`, int64(2)},
+		{`var log = ""; var c = channel(); fun f() { log += "f"; send(c, nil); } spawn f(); log += "main"; receive(c); print log;`, "mainf"},
+		{`var results = channel(4); fun worker(jobs) { var j = receive(jobs); while (j != nil) { send(results, j * j); j = receive(jobs); } } var jobs = channel(); spawn worker(jobs); spawn worker(jobs); for (var i in range(1, 4)) send(jobs, i); close(jobs); var s = 0; for (var i in range(3)) s += receive(results); print s;`, int64(14)},
+		{`try { spawn clock(); } catch (e) { print e.message; }`, "Can only spawn Lox functions."},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var c = channel(2); send(c, 1); send(c, 2); print receive(c) + receive(c);`, int64(3)},
+		{`var c = channel(1); send(c, "a"); close(c); receive(c); print receive(c);`, nil},
+		{`var c = channel(); close(c); try { send(c, 1); } catch (e) { print e.message; }`, "Cannot send on a closed channel."},
+		{`var c = channel(1); fun f() { send(c, 1); send(c, 2); } spawn f(); receive(c); print receive(c);`, int64(2)},
+		{`var a = channel(); var b = channel(); fun f() { send(b, "b"); } spawn f(); print select(a, b)[1];`, "b"},
+		{`var a = channel(); var b = channel(1); print select(a, [b, 1])[0];`, int64(1)},
+		{`var a = channel(); fun f() { print select(a, channel())[1]; } spawn f(); send(a, "sent");`, "sent"},
+		{`var a = channel(); var b = channel(); fun f() { select(a, b); } spawn f(); fun g() { send(b, 1); send(a, 2); } spawn g(); try { receive(channel()); } catch (e) { print "stuck"; }`, "stuck"},
+		{`fun g(c) { yield receive(c); } try { g(channel()).next(); } catch (e) { print e.message; }`, "Cannot receive inside a callback or a generator."},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestDeadlock(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`try { receive(channel()); } catch (e) { print e.message; }`, "Deadlock: every fiber is blocked.\n  main: waiting to receive at line 1"},
+		{`fun f() {} spawn f(); try { send(channel(), 1); } catch (e) { print e.message; }`, "Deadlock: every fiber is blocked.\n  main: waiting to send at line 1"},
+		{"fun f(c) {\nreceive(c);\n}\nspawn f(channel());\ntry { receive(channel()); } catch (e) { print e.message; }",
+			"Deadlock: every fiber is blocked.\n  main: waiting to receive at line 5\n  f: waiting to receive at line 2"},
+		{`var c = channel(); try { receive(c); } catch (e) {} fun f() { send(c, 1); } spawn f(); print "recovered";`, "recovered"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
@@ -23,13 +23,196 @@ func TestFunction(t *testing.T) {
 		{`print clock;`, "<native fn>"},
 		{`fun hello() { print "Hello"; } hello();`, "Hello"},
 		{`fun fib(n) { if(n < 2) return n; return fib(n - 1) + fib(n - 2);}
-				 print fib(8);`, 21.0},
+				 print fib(8);`, int64(21)},
 		{`fun isEven(n) { if (n == 0) return true; return isOdd(n - 1); }
 				 fun isOdd(n) { return isEven(n - 1); } print isEven(4);`, true},
 		{`fun isEven(n) { if (n == 0) return true; return isOdd(n - 1); }
 				 fun isOdd(n) { return isEven(n - 1); } print isOdd(3);`, true},
 		{`fun add(a, b) { return a + b; } fun mul(a, b) { return a * b; }
-			     print add(mul(2, 4), 5);`, 13.0},
+			     print add(mul(2, 4), 5);`, int64(13)},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestClosure(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun outer() { var x = "outside"; fun inner() { print x; } inner(); } outer();`, "outside"},
+		{`fun outer() { var x = "outside"; fun inner() { print x; } return inner; }
+				 var closure = outer(); closure();`, "outside"},
+		{`fun makeCounter() { var i = 0; fun count() { i = i + 1; return i; } return count; }
+				 var counter = makeCounter(); counter(); counter(); print counter();`, int64(3)},
+		{`fun makeCounter() { var i = 0; fun count() { i = i + 1; return i; } return count; }
+				 var a = makeCounter(); var b = makeCounter(); a(); a(); print b();`, int64(1)},
+		{`fun outer() { var x = "value"; fun middle() { fun inner() { print x; } return inner; } return middle; }
+				 outer()()();`, "value"},
+		{`var globalSet; var globalGet;
+				 fun main() { var a = "initial"; fun set() { a = "updated"; } fun get() { print a; }
+				 globalSet = set; globalGet = get; }
+				 main(); globalSet(); globalGet();`, "updated"},
+		{`var f; { var local = "local"; fun g() { print local; } f = g; } f();`, "local"},
+		{`var f; for(var i = 0; i < 3; i = i + 1) { var j = i; fun g() { print j; } if (i == 1) f = g; } f();`, int64(1)},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestAnonymousFunction(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var add = fun (a, b) { return a + b; }; print add(1, 2);`, int64(3)},
+		{`print fun (x) { return x * 2; }(21);`, int64(42)},
+		{`fun (x) { print x; }("called");`, "called"},
+		{`fun apply(f, x) { return f(x); } print apply(fun (x) { return x + 1; }, 1);`, int64(2)},
+		{`var f = (a, b) => a + b; print f(3, 4);`, int64(7)},
+		{`var f = () => "nothing"; print f();`, "nothing"},
+		{`var f = (x) => { var y = x * 2; return y + 1; }; print f(3);`, int64(7)},
+		{`var f = (x) => (y) => x + y; print f(1)(2);`, int64(3)},
+		{`var f = (x) => x > 0 ? "pos" : "neg"; print f(-1);`, "neg"},
+		{`print (1 + 2) * 3;`, int64(9)},
+		{`var xs = [3, 1, 2]; sort(xs, (a, b) => b - a); print xs[0];`, int64(3)},
+		{`fun counter() { var n = 0; return () => ++n; } var c = counter(); c(); print c();`, int64(2)},
+		{`var f = fun () {}; print f();`, nil},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestDefaultParameters(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun f(a, b = 10) { return a + b; } print f(1);`, int64(11)},
+		{`fun f(a, b = 10) { return a + b; } print f(1, 2);`, int64(3)},
+		{`fun f(a = 1, b = a + 1) { return b; } print f();`, int64(2)},
+		{`fun f(a = 1, b = a + 1) { return b; } print f(5);`, int64(6)},
+		{`fun f(a = nil) { return a; } print f(false);`, false},
+		{`var n = 0; fun f(a = n++) { return a; } f(); f(); f(1); print n;`, int64(2)},
+		{`fun f(xs = []) { push(xs, 1); return len(xs); } f(); print f();`, int64(1)},
+		{`var f = (a, b = "!") => a + b; print f("hi");`, "hi!"},
+		{`class A { init(x = 3) { this.x = x; } } print A().x;`, int64(3)},
+		{`class A { m(x = "m") { return x; } } print A().m();`, "m"},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun f(a, b) { return a - b; } print f(b: 2, a: 1);`, int64(-1)},
+		{`fun f(a, b) { return a - b; } print f(5, b: 2);`, int64(3)},
+		{`fun f(a, b = 2, c = 3) { return "${a}${b}${c}"; } print f(1, c: 9);`, "129"},
+		{`fun f(a, b = 2, c = 3) { return "${a}${b}${c}"; } print f(c: 7, a: 0);`, "027"},
+		{`fun f(a) { return a; } var b = true; print f(a: b ? 1 : 2);`, int64(1)},
+		{`fun f(a) { return a; } print f({"a": 1}["a"]);`, int64(1)},
+		{`class P { init(x, y = 0) { this.x = x; this.y = y; } } var p = P(y: 5, x: 1); print p.y - p.x;`, int64(4)},
+		{`class A { m(a, b) { return a + b; } } print A().m(b: "b", a: "a");`, "ab"},
+		{`class A { m(a, b) { return a + b; } } class B < A { m() { return super.m(b: 1, a: 2); } } print B().m();`, int64(3)},
+		{`var f = (x, y) => x / y; print f(y: 2, x: 1);`, 0.5},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestVariadic(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun f(...xs) { return len(xs); } print f();`, int64(0)},
+		{`fun f(...xs) { return len(xs); } print f(1, 2, 3);`, int64(3)},
+		{`fun f(a, ...xs) { return a + len(xs); } print f(10, "a", "b");`, int64(12)},
+		{`fun f(a, ...xs) { return xs[-1]; } print f(1, 2, 3);`, int64(3)},
+		{`fun f(a, b = 2, ...xs) { return "${a}${b}${len(xs)}"; } print f(1);`, "120"},
+		{`fun f(a, b = 2, ...xs) { return "${a}${b}${len(xs)}"; } print f(1, 5, 6, 7);`, "152"},
+		{`fun f(a, b = 2, ...xs) { return "${a}${b}${len(xs)}"; } print f(b: 3, a: 1);`, "130"},
+		{`fun sum(...xs) { var n = 0; for (var i = 0; i < len(xs); i++) n += xs[i]; return n; } print sum(1, 2, 3, 4);`, int64(10)},
+		{`var f = (...xs) => len(xs); print f(1, 2);`, int64(2)},
+		{`class A { m(...xs) { return len(xs); } } print A().m(1, 2, 3);`, int64(3)},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun f(a, b, c) { return a + b + c; } var xs = [1, 2, 3]; print f(...xs);`, int64(6)},
+		{`fun f(a, b, c) { return "${a}${b}${c}"; } print f(1, ...[2, 3]);`, "123"},
+		{`fun f(a, b, c) { return "${a}${b}${c}"; } print f(...[1], 2, ...[3]);`, "123"},
+		{`fun f(...xs) { return len(xs); } print f(...[], ...[1, 2], 3);`, int64(3)},
+		{`fun f(a, ...xs) { return xs[0]; } print f(...[1, 2, 3]);`, int64(2)},
+		{`class A { m(a, b) { return a - b; } } print A().m(...[5, 3]);`, int64(2)},
+		{`var xs = [1]; push(...[xs, 2]); print xs[1];`, int64(2)},
+		{`fun f(a, b) { return a - b; } print f(...(5, 3));`, int64(2)},
+		{`fun pair() { return 4, 2; } fun f(a, b, c) { return a * b + c; } print f(...pair(), 1);`, int64(9)},
+		{`fun f(...xs) { return xs; } try { f(...1); } catch (e) { print e.message; }`, "Can only spread a list or a tuple."},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestMultipleReturn(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun f() { return 1, 2; } print "${f()}";`, "(1, 2)"},
+		{`fun f() { return 1, "a", nil; } print "${f()}";`, `(1, "a", nil)`},
+		{`fun f() { return 1, 2; } print f()[1];`, int64(2)},
+		{`fun f() { return 1, 2, 3; } print len(f());`, int64(3)},
+		{`fun f() { return 1, 2; } print f() == (1, 2);`, true},
+		{`fun f() { return 1, 2; } print f() == (2, 1);`, false},
+		{`fun f() { try { return 1, 2; } finally { print 0; } } print "${f()}";`, "(1, 2)"},
+		{`print "${(1,)}";`, "(1,)"},
+		{`var n = 0; for (var x in (1, 2, 3)) n += x; print n;`, int64(6)},
+		{`var t = (1, 2); try { t[0] = 3; } catch (e) { print e.message; }`, "Tuples are immutable."},
+	}
+
+	for _, test := range tests {
+		RunFunctionTest(t, test.source, test.result)
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun divmod(a, b) { return a ~/ b, a % b; } var (q, r) = divmod(7, 2); print "${q} ${r}";`, "3 1"},
+		{`{ var (a, b, c) = [1, 2, 3]; print a + b + c; }`, int64(6)},
+		{`var a = 1; var b = 2; (a, b) = (b, a); print "${a} ${b}";`, "2 1"},
+		{`{ var a = 1; var b = 2; (a, b) = (b, a); print "${a} ${b}"; }`, "2 1"},
+		{`var a; var b; print "${(a, b) = [3, 4]}";`, "(3, 4)"},
+		{`fun f() { var x = 1; var y = 2; fun g() { (x, y) = (y, x); } g(); return x; } print f();`, int64(2)},
+		{`try { var (a, b) = (1, 2, 3); } catch (e) { print e.message; }`, "Expected 2 values to unpack but got 3."},
+		{`var a; var b; try { (a, b) = [1]; } catch (e) { print e.message; }`, "Expected 2 values to unpack but got 1."},
+		{`try { var (a, b) = 1; } catch (e) { print e.message; }`, "Can only unpack tuples and lists."},
 	}
 
 	for _, test := range tests {
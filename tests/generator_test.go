@@ -0,0 +1,36 @@
+package tests
+
+import "testing"
+
+func TestGenerator(t *testing.T) {
+	fib := `fun fib() { var a = 0; var b = 1; while (true) { yield a; var t = a + b; a = b; b = t; } }`
+
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun g() { yield 1; yield 2; } var gen = g(); gen.next(); print gen.next();`, int64(2)},
+		{`fun g() { yield 1; } var gen = g(); gen.next(); print gen.next();`, nil},
+		{`fun g() { yield 1; } var gen = g(); gen.next(); print gen.done();`, false},
+		{`fun g() { yield 1; } var gen = g(); gen.next(); gen.next(); print gen.done();`, true},
+		{`fun g() { yield 1; return "result"; } var gen = g(); gen.next(); print gen.next();`, "result"},
+		{`fun g() { yield 1; return "result"; } var gen = g(); gen.next(); gen.next(); print gen.next();`, nil},
+		{`fun g(n) { if (n == 0) return "empty"; yield n; } print g(0).next();`, "empty"},
+		{`fun g() { yield 1; return 2; } var s = 0; for (var x in g()) s += x; print s;`, int64(1)},
+		{`fun g() { print "ran"; yield; } var gen = g(); print "created";`, "created"},
+		{`fun g(n) { for (var i in range(n)) yield i * i; } var s = 0; for (var x in g(4)) s += x; print s;`, int64(14)},
+		{`fun acc() { var total = 0; while (true) total += yield total; } var a = acc(); a.next(); a.next(2); print a.next(3);`, int64(5)},
+		{fib + `var n = 0; for (var x in fib()) { if (x > 50) break; n = x; } print n;`, int64(34)},
+		{fib + `fun double(g) { for (var x in g) yield x * 2; } var d = double(fib()); d.next(); d.next(); d.next(); print d.next();`, int64(4)},
+		{`class Pair { init(a, b) { this.a = a; this.b = b; } items() { yield this.a; yield this.b; } } var s = ""; for (var x in Pair("a", "b").items()) s += x; print s;`, "ab"},
+		{`var gs = []; fun g(x) { yield x; } push(gs, g(1)); push(gs, g(2)); print gs[1].next() + gs[0].next();`, int64(3)},
+		{`fun g() { var x = 1; var f = () => x; yield f; x = 2; yield f; } var gen = g(); var f = gen.next(); gen.next(); print f();`, int64(2)},
+		{`fun g() { yield 1; throw "oops"; } var gen = g(); gen.next(); try { gen.next(); } catch (e) { print e; }`, "oops"},
+		{`fun g() { try { yield 1; throw "x"; } catch (e) { yield e + "!"; } } var gen = g(); gen.next(); print gen.next();`, "x!"},
+		{`var g; g = (fun() { yield g.next(); })(); try { g.next(); } catch (e) { print e.message; }`, "Generator anonymous is already running."},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
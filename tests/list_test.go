@@ -0,0 +1,76 @@
+package tests
+
+import "testing"
+
+func TestListIndex(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var xs = [1, 2, 3]; print xs[0];`, int64(1)},
+		{`var xs = [1, 2, 3]; print xs[-1];`, int64(3)},
+		{`var xs = [1, 2, 3,]; print len(xs);`, int64(3)},
+		{`print len([]);`, int64(0)},
+		{`var xs = [1, 2, 3]; xs[1] = "two"; print xs[1];`, "two"},
+		{`var xs = [1, 2, 3]; xs[-3] = 0; print xs[0];`, int64(0)},
+		{`var xs = [[1, 2], [3, 4]]; print xs[1][0];`, int64(3)},
+		{`var xs = [[1, 2], [3, 4]]; xs[0][1] = 5; print xs[0][1];`, int64(5)},
+		{`var xs = [1, 2, 3]; print xs[0] = 7;`, int64(7)},
+		{`print "hello"[1];`, "e"},
+		{`print "hello"[-1];`, "o"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestListSlice(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var xs = [1, 2, 3, 4]; print len(xs[1:3]);`, int64(2)},
+		{`var xs = [1, 2, 3, 4]; print xs[1:3][0];`, int64(2)},
+		{`var xs = [1, 2, 3, 4]; print len(xs[:]);`, int64(4)},
+		{`var xs = [1, 2, 3, 4]; print xs[2:][0];`, int64(3)},
+		{`var xs = [1, 2, 3, 4]; print len(xs[:-1]);`, int64(3)},
+		{`var xs = [1, 2, 3, 4]; print len(xs[3:1]);`, int64(0)},
+		{`var xs = [1, 2, 3, 4]; print len(xs[-10:10]);`, int64(4)},
+		{`var xs = [1, 2]; var ys = xs[:]; ys[0] = 9; print xs[0];`, int64(1)},
+		{`print "hello"[1:3];`, "el"},
+		{`print "hello"[:-2];`, "hel"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestListNatives(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var xs = []; push(xs, 1); push(xs, 2); print len(xs);`, int64(2)},
+		{`var xs = [1, 2, 3]; print pop(xs);`, int64(3)},
+		{`var xs = [1, 2, 3]; pop(xs); print len(xs);`, int64(2)},
+		{`var xs = [1, 3]; insert(xs, 1, 2); print xs[1];`, int64(2)},
+		{`var xs = [1, 2]; insert(xs, 2, 3); print xs[2];`, int64(3)},
+		{`var xs = [2, 3]; insert(xs, 0, 1); print xs[0];`, int64(1)},
+		{`var xs = [1, 2, 3]; print remove(xs, 0);`, int64(1)},
+		{`var xs = [1, 2, 3]; remove(xs, -1); print len(xs);`, int64(2)},
+		{`var xs = [3, 1, 2]; sort(xs); print xs[0];`, int64(1)},
+		{`var xs = ["b", "c", "a"]; sort(xs); print xs[2];`, "c"},
+		{`fun desc(a, b) { return b - a; } var xs = [3, 1, 2]; sort(xs, desc); print xs[0];`, int64(3)},
+		{`class P { init(n) { this.n = n; } } fun byN(a, b) { return a.n - b.n; }
+				 var xs = [P(2), P(3), P(1)]; sort(xs, byN); print xs[0].n;`, int64(1)},
+		{`var xs = [1, 2, 3]; reverse(xs); print xs[0];`, int64(3)},
+		{`print len("héllo");`, int64(5)},
+		{`var xs = [1, 2, 3]; var sum = 0; for (var i = 0; i < len(xs); i = i + 1) sum = sum + xs[i]; print sum;`, int64(6)},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
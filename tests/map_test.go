@@ -0,0 +1,64 @@
+package tests
+
+import "testing"
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var m = {"a": 1, "b": 2}; print m["b"];`, int64(2)},
+		{`var m = {}; m["a"] = "x"; print m["a"];`, "x"},
+		{`var m = {"a": 1}; m["a"] = 2; print m["a"];`, int64(2)},
+		{`var m = {"a": 1,}; print len(m);`, int64(1)},
+		{`print len({});`, int64(0)},
+		{`var m = {1: "one", true: "yes", nil: "nothing"}; print m[1] + m[true] + m[nil];`, "oneyesnothing"},
+		{`var m = {0: "zero"}; print m[-0];`, "zero"},
+		{`var m = {"a" + "b": 1 + 1}; print m["ab"];`, int64(2)},
+		{`var m = {"inner": {"x": [1, 2]}}; print m["inner"]["x"][1];`, int64(2)},
+		{`var m = {"a": 1}; print m["b"] = 2;`, int64(2)},
+		{`{ var m = {"a": 1}; print m["a"]; }`, int64(1)},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestMapNatives(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var m = {"b": 1, "a": 2}; print keys(m)[0];`, "b"},
+		{`var m = {"b": 1, "a": 2}; print values(m)[1];`, int64(2)},
+		{`var m = {"a": 1}; print has(m, "a");`, true},
+		{`var m = {"a": 1}; print has(m, "b");`, false},
+		{`var m = {"a": 1}; print delete(m, "a");`, true},
+		{`var m = {"a": 1}; delete(m, "a"); print has(m, "a");`, false},
+		{`var m = {"a": 1}; print delete(m, "b");`, false},
+		{`var m = {"a": 1, "b": 2, "c": 3}; delete(m, "b"); print keys(m)[1];`, "c"},
+		{`var m = {"a": 1, "b": 2}; delete(m, "a"); print len(m);`, int64(1)},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`fun f() {} print f == f;`, true},
+		{`fun f() {} fun g() {} print f == g;`, false},
+		{`print len == len;`, true},
+		{`print len == keys;`, false},
+		{`var xs = []; print xs == xs;`, true},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
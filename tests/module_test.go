@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"golox/vm"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func RunModuleTest(t *testing.T, files map[string]string, result interface{}) {
+	dir, err := ioutil.TempDir("", "golox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vmachine := vm.New()
+	vmachine.SearchPath = []string{filepath.Join(dir, "lib")}
+	vmachine.InterpretFile(filepath.Join(dir, "main.lox"))
+	if vmachine.Out != result {
+		t.Errorf("Incorrect result for source '%s'. Expected: %v. Got: %v.", files["main.lox"], result, vmachine.Out)
+	}
+}
+
+func TestImport(t *testing.T) {
+	mathLib := `export fun square(x) { return x * x; } export var pi = 3; var secret = 7;`
+
+	tests := []struct {
+		files  map[string]string
+		result interface{}
+	}{
+		{map[string]string{
+			"main.lox": `import "math.lox" as m; print m.square(4);`,
+			"math.lox": mathLib,
+		}, int64(16)},
+		{map[string]string{
+			"main.lox": `import { square, pi } from "math.lox"; print square(pi);`,
+			"math.lox": mathLib,
+		}, int64(9)},
+		{map[string]string{
+			"main.lox": `import "math.lox" as m; try { print m.secret; } catch (e) { print e.message; }`,
+			"math.lox": mathLib,
+		}, "Module 'math' does not export 'secret'."},
+		{map[string]string{
+			"main.lox": `import "math.lox" as m; print m.pi;`,
+			"math.lox": mathLib,
+		}, int64(3)},
+		{map[string]string{
+			"main.lox":    `var x = "main"; import "counter.lox"; import "counter.lox" as c; print c.count + x;`,
+			"counter.lox": `var x = "counter"; export var count = "ran "; count = count + x;`,
+		}, "ran countermain"},
+		{map[string]string{
+			"main.lox": `import "a.lox" as a; import "b.lox" as b; print a.runs;`,
+			"a.lox":    `export var runs = 0; runs = runs + 1;`,
+			"b.lox":    `import "a.lox" as a;`,
+		}, int64(1)},
+		{map[string]string{
+			"main.lox":      `import "util/str.lox" as s; print s.greet();`,
+			"util/str.lox":  `import { name } from "name.lox"; export fun greet() { return "hi " + name; }`,
+			"util/name.lox": `export var name = "lox";`,
+		}, "hi lox"},
+		{map[string]string{
+			"main.lox":       `import { version } from "shared.lox"; print version;`,
+			"lib/shared.lox": `export var version = len("1.0");`,
+		}, int64(3)},
+		{map[string]string{
+			"main.lox":  `import "empty.lox" as e; print "imported";`,
+			"empty.lox": ``,
+		}, "imported"},
+		{map[string]string{
+			"main.lox": `try { import "missing.lox"; } catch (e) { print e.message; }`,
+		}, "Cannot find module 'missing.lox'."},
+	}
+
+	for _, test := range tests {
+		RunModuleTest(t, test.files, test.result)
+	}
+}
@@ -36,24 +36,40 @@ abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890_`
 }
 
 func TestKeywords(t *testing.T) {
-	source := "and class else false for fun if nil or return super this true var while"
+	source := "and assert break case catch class const continue else enum export false finally for fun if import in match nil or return spawn super this throw true try var while yield"
 
 	expected := []token.Token{
 		{token.AND, "and", nil, 1},
+		{token.ASSERT, "assert", nil, 1},
+		{token.BREAK, "break", nil, 1},
+		{token.CASE, "case", nil, 1},
+		{token.CATCH, "catch", nil, 1},
 		{token.CLASS, "class", nil, 1},
+		{token.CONST, "const", nil, 1},
+		{token.CONTINUE, "continue", nil, 1},
 		{token.ELSE, "else", nil, 1},
+		{token.ENUM, "enum", nil, 1},
+		{token.EXPORT, "export", nil, 1},
 		{token.FALSE, "false", nil, 1},
+		{token.FINALLY, "finally", nil, 1},
 		{token.FOR, "for", nil, 1},
 		{token.FUN, "fun", nil, 1},
 		{token.IF, "if", nil, 1},
+		{token.IMPORT, "import", nil, 1},
+		{token.IN, "in", nil, 1},
+		{token.MATCH, "match", nil, 1},
 		{token.NIL, "nil", nil, 1},
 		{token.OR, "or", nil, 1},
 		{token.RETURN, "return", nil, 1},
+		{token.SPAWN, "spawn", nil, 1},
 		{token.SUPER, "super", nil, 1},
 		{token.THIS, "this", nil, 1},
+		{token.THROW, "throw", nil, 1},
 		{token.TRUE, "true", nil, 1},
+		{token.TRY, "try", nil, 1},
 		{token.VAR, "var", nil, 1},
 		{token.WHILE, "while", nil, 1},
+		{token.YIELD, "yield", nil, 1},
 		{token.EOF, "", nil, 1},
 	}
 
@@ -67,11 +83,11 @@ func TestNumbers(t *testing.T) {
 123.`
 
 	expected := []token.Token{
-		{token.NUMBER, "123", 123.0, 1},
+		{token.NUMBER, "123", int64(123), 1},
 		{token.NUMBER, "123.456", 123.456, 2},
 		{token.DOT, ".", nil, 3},
-		{token.NUMBER, "456", 456.0, 3},
-		{token.NUMBER, "123", 123.0, 4},
+		{token.NUMBER, "456", int64(456), 3},
+		{token.NUMBER, "123", int64(123), 4},
 		{token.DOT, ".", nil, 4},
 		{token.EOF, "", nil, 4},
 	}
@@ -138,3 +154,149 @@ end`
 
 	runScanner(t, source, expected)
 }
+
+func TestBrackets(t *testing.T) {
+	source := "[]:"
+
+	expected := []token.Token{
+		{token.LEFT_BRACKET, "[", nil, 1},
+		{token.RIGHT_BRACKET, "]", nil, 1},
+		{token.COLON, ":", nil, 1},
+		{token.EOF, "", nil, 1},
+	}
+
+	runScanner(t, source, expected)
+}
+
+func TestStringEscapes(t *testing.T) {
+	source := `"line\nbreak" "tab\there" "quote\"d" "back\\slash" "\u{48}\u{1F600}" "nul\0"`
+
+	expected := []token.Token{
+		{token.STRING, `"line\nbreak"`, "line\nbreak", 1},
+		{token.STRING, `"tab\there"`, "tab\there", 1},
+		{token.STRING, `"quote\"d"`, "quote\"d", 1},
+		{token.STRING, `"back\\slash"`, "back\\slash", 1},
+		{token.STRING, `"\u{48}\u{1F600}"`, "H\U0001F600", 1},
+		{token.STRING, `"nul\0"`, "nul\x00", 1},
+		{token.EOF, "", nil, 1},
+	}
+
+	runScanner(t, source, expected)
+}
+
+func TestUnicode(t *testing.T) {
+	source := `café naïve_1 π "ünïcödé" 日本`
+
+	expected := []token.Token{
+		{token.IDENTIFIER, "café", nil, 1},
+		{token.IDENTIFIER, "naïve_1", nil, 1},
+		{token.IDENTIFIER, "π", nil, 1},
+		{token.STRING, `"ünïcödé"`, "ünïcödé", 1},
+		{token.IDENTIFIER, "日本", nil, 1},
+		{token.EOF, "", nil, 1},
+	}
+
+	runScanner(t, source, expected)
+}
+
+func TestStringInterpolation(t *testing.T) {
+	source := `"a ${b} c ${ {} } d" "\${x}"`
+
+	expected := []token.Token{
+		{token.INTERPOLATION, `"a ${`, "a ", 1},
+		{token.IDENTIFIER, "b", nil, 1},
+		{token.INTERPOLATION, `} c ${`, " c ", 1},
+		{token.LEFT_BRACE, "{", nil, 1},
+		{token.RIGHT_BRACE, "}", nil, 1},
+		{token.STRING, `} d"`, " d", 1},
+		{token.STRING, `"\${x}"`, "${x}", 1},
+		{token.EOF, "", nil, 1},
+	}
+
+	runScanner(t, source, expected)
+}
+
+func TestComments(t *testing.T) {
+	source := `// comment
+a ~/ b
+c /* block
+comment */ d
+{ // comment after a brace
+e // comment after an identifier
+f() // comment after a parenthesis
+1 // comment after a literal
+% ** ~ /`
+
+	expected := []token.Token{
+		{token.IDENTIFIER, "a", nil, 2},
+		{token.TILDE_SLASH, "~/", nil, 2},
+		{token.IDENTIFIER, "b", nil, 2},
+		{token.IDENTIFIER, "c", nil, 3},
+		{token.IDENTIFIER, "d", nil, 4},
+		{token.LEFT_BRACE, "{", nil, 5},
+		{token.IDENTIFIER, "e", nil, 6},
+		{token.IDENTIFIER, "f", nil, 7},
+		{token.LEFT_PAREN, "(", nil, 7},
+		{token.RIGHT_PAREN, ")", nil, 7},
+		{token.NUMBER, "1", int64(1), 8},
+		{token.PERCENT, "%", nil, 9},
+		{token.STAR_STAR, "**", nil, 9},
+		{token.TILDE, "~", nil, 9},
+		{token.SLASH, "/", nil, 9},
+		{token.EOF, "", nil, 9},
+	}
+
+	runScanner(t, source, expected)
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	source := "& | ^ ~ << >> <= >="
+
+	expected := []token.Token{
+		{token.AMPERSAND, "&", nil, 1},
+		{token.PIPE, "|", nil, 1},
+		{token.CARET, "^", nil, 1},
+		{token.TILDE, "~", nil, 1},
+		{token.LESS_LESS, "<<", nil, 1},
+		{token.GREATER_GREATER, ">>", nil, 1},
+		{token.LESS_EQUAL, "<=", nil, 1},
+		{token.GREATER_EQUAL, ">=", nil, 1},
+		{token.EOF, "", nil, 1},
+	}
+
+	runScanner(t, source, expected)
+}
+
+func TestAssignmentOperators(t *testing.T) {
+	source := "+= -= *= /= %= ++ -- + -"
+
+	expected := []token.Token{
+		{token.PLUS_EQUAL, "+=", nil, 1},
+		{token.MINUS_EQUAL, "-=", nil, 1},
+		{token.STAR_EQUAL, "*=", nil, 1},
+		{token.SLASH_EQUAL, "/=", nil, 1},
+		{token.PERCENT_EQUAL, "%=", nil, 1},
+		{token.PLUS_PLUS, "++", nil, 1},
+		{token.MINUS_MINUS, "--", nil, 1},
+		{token.PLUS, "+", nil, 1},
+		{token.MINUS, "-", nil, 1},
+		{token.EOF, "", nil, 1},
+	}
+
+	runScanner(t, source, expected)
+}
+
+func TestEllipsis(t *testing.T) {
+	source := "...xs a.b"
+
+	expected := []token.Token{
+		{token.ELLIPSIS, "...", nil, 1},
+		{token.IDENTIFIER, "xs", nil, 1},
+		{token.IDENTIFIER, "a", nil, 1},
+		{token.DOT, ".", nil, 1},
+		{token.IDENTIFIER, "b", nil, 1},
+		{token.EOF, "", nil, 1},
+	}
+
+	runScanner(t, source, expected)
+}
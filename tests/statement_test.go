@@ -62,7 +62,7 @@ func TestIf(t *testing.T) {
 		{`var a  = false; if(a = true) print a;`, true},
 		{`if(false) print "bad"; else print "false";`, "false"},
 		{`if(nil) print "bad"; else print "nil";`, "nil"},
-		{`if(0) print 0;`, 0.0},
+		{`if(0) print 0;`, int64(0)},
 		{`if("") print "empty";`, "empty"},
 		{`if(true) print "good";`, "good"},
 		{`if(true) print "good"; else print "bad";`, "good"},
@@ -82,11 +82,11 @@ func TestFor(t *testing.T) {
 		source string
 		result interface{}
 	}{
-		{`var sum = 0; for(var i = 0; i < 11; i = i + 1) sum = sum + i; print sum;`, 55.0},
-		{`var sum = 0; for(var i = 0; i < 11; i = i + 1) { sum = sum + i; } print sum;`, 55.0},
-		{`var sum = 0; var i = 0; for(; i < 11; i = i + 1) sum = sum + i; print sum;`, 55.0},
-		{`var sum = 0; for(var i = 0; i < 11;) { sum = sum + i; i = i + 1; } print sum;`, 55.0},
-		{`var i = "before"; for(var i = 0; i < 1; i = i + 1) print i;`, 0.0},
+		{`var sum = 0; for(var i = 0; i < 11; i = i + 1) sum = sum + i; print sum;`, int64(55)},
+		{`var sum = 0; for(var i = 0; i < 11; i = i + 1) { sum = sum + i; } print sum;`, int64(55)},
+		{`var sum = 0; var i = 0; for(; i < 11; i = i + 1) sum = sum + i; print sum;`, int64(55)},
+		{`var sum = 0; for(var i = 0; i < 11;) { sum = sum + i; i = i + 1; } print sum;`, int64(55)},
+		{`var i = "before"; for(var i = 0; i < 1; i = i + 1) print i;`, int64(0)},
 		{`for(var i = 0; i < 1; i = i + 1) {} var i = "after"; print i;`, "after"},
 	}
 
@@ -95,12 +95,87 @@ func TestFor(t *testing.T) {
 	}
 }
 
+func TestForIn(t *testing.T) {
+	counter := `class Counter {
+		init(n) { this.i = 0; this.n = n; }
+		hasNext() { return this.i < this.n; }
+		next() { this.i = this.i + 1; return this.i; }
+	}`
+
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var s = ""; for (var c in "abc") s = c + s; print s;`, "cba"},
+		{`var n = 0; for (var x in [1, 2, 3]) n += x; print n;`, int64(6)},
+		{`var s = ""; for (var k in {"a": 1, "b": 2}) s += k; print s;`, "ab"},
+		{`var n = 0; for (var i in range(5)) n += i; print n;`, int64(10)},
+		{`var n = 0; for (var i in range(2, 8, 3)) n = n * 10 + i; print n;`, int64(25)},
+		{`var n = 0; for (var i in range(3, 0, -1)) n = n * 10 + i; print n;`, int64(321)},
+		{`var n = 0; for (var x in range(0, 1, 0.25)) n += x; print n;`, 1.5},
+		{counter + `var n = 0; for (var x in Counter(4)) n += x; print n;`, int64(10)},
+		{counter + `class Wrapper { iter() { return Counter(3); } } var n = 0; for (const x in Wrapper()) n += x; print n;`, int64(6)},
+		{`var n = 0; for (var x in range(10)) { if (x == 4) break; if (x % 2 == 0) continue; n += x; } print n;`, int64(4)},
+		{`var fs = []; for (var x in [1, 2]) push(fs, () => x); print fs[0]() + fs[1]();`, int64(3)},
+		{`fun first(xs) { for (var x in xs) if (x > 2) return x; } print first([1, 5, 9]);`, int64(5)},
+		{`var n = 0; for (var i in range(3)) for (var j in range(3)) n += 1; print n;`, int64(9)},
+		{`try { for (var x in 1) print x; } catch (e) { print e.message; }`, "Cannot iterate over 1."},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	classify := `fun classify(v) {
+		match (v) {
+			case 0 => return "zero";
+			case "start" | "go" => return "start";
+			case -1 | 1.5 => return "special";
+			case nil | false => return "falsy";
+			case n if n > 100 => return "big";
+			case _ => return "other";
+		}
+	}`
+
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{classify + `print classify(0);`, "zero"},
+		{classify + `print classify("go");`, "start"},
+		{classify + `print classify(-1);`, "special"},
+		{classify + `print classify(1.5);`, "special"},
+		{classify + `print classify(false);`, "falsy"},
+		{classify + `print classify(101);`, "big"},
+		{classify + `print classify(7);`, "other"},
+		{`match (3) { case n => print n * 2; }`, int64(6)},
+		{`var r = "none"; match ("x") { case "y" => r = "y"; } print r;`, "none"},
+		{`var r = 0; match (5) { case 5 if false => r = 1; case 5 => r = 2; } print r;`, int64(2)},
+		{`var r = 0; match (5) { case n if n < 0 => r = 1; case n if n > 0 => r = n; } print r;`, int64(5)},
+		{`var f; match (4) { case n => f = () => n + 1; } print f();`, int64(5)},
+		{`var s = 0; for (var i in range(5)) { match (i) { case 1 => continue; case 4 => break; case _ => s += i; } } print s;`, int64(5)},
+		{`var a = 1; match (2) { case a => print a; } `, int64(2)},
+		{`var a = 1; match (2) { case a => a = 3; } print a;`, int64(1)},
+		{`var y = true; match (2) { case x if (y) => print x; }`, int64(2)},
+		{`var a = true; var b = false; var r = 0; match (1) { case 1 if (a and b) => r = 1; case 1 if (a or b) => r = 2; } print r;`, int64(2)},
+		{`match (3) { case n if (n > 1) and (n < 5) => print n; }`, int64(3)},
+		{`fun any(f) { return f(2); } match (1) { case n if any((v) => v > n) => print "arrow"; }`, "arrow"},
+		{`match (1) { case n if ((v) => v == n)(1) => print "called"; }`, "called"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
 func TestWhile(t *testing.T) {
 	tests := []struct {
 		source string
 		result interface{}
 	}{
-		{`var sum = 0; var i = 0; while(sum < 55) { i = i + 1; sum = sum + i; } print i;`, 10.0},
+		{`var sum = 0; var i = 0; while(sum < 55) { i = i + 1; sum = sum + i; } print i;`, int64(10)},
 		{`while(false) print "bad"; print "good";`, "good"},
 	}
 
@@ -108,3 +183,132 @@ func TestWhile(t *testing.T) {
 		RunStatementTest(t, test.source, test.result)
 	}
 }
+
+func TestBreakContinue(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`var i = 0; while (true) { if (i == 3) break; i = i + 1; } print i;`, int64(3)},
+		{`var sum = 0; for (var i = 0; i < 10; i = i + 1) { if (i == 5) break; sum = sum + i; } print sum;`, int64(10)},
+		{`var sum = 0; for (var i = 0; i < 5; i = i + 1) { if (i == 2) continue; sum = sum + i; } print sum;`, int64(8)},
+		{`var sum = 0; var i = 0; while (i < 5) { i = i + 1; if (i == 2) continue; sum = sum + i; } print sum;`, int64(13)},
+		{`var sum = 0; for (var i = 0; i < 3; i = i + 1) { var a = i; { var b = a; if (b == 1) continue; sum = sum + b; } } print sum;`, int64(2)},
+		{`for (var i = 0; i < 3; i = i + 1) { var a = "a"; var b = "b"; if (i == 1) break; } var after = "after"; print after;`, "after"},
+		{`var n = 0; for (var i = 0; i < 3; i = i + 1) { for (var j = 0; j < 3; j = j + 1) { if (j == 1) break; n = n + 1; } } print n;`, int64(3)},
+		{`var n = 0; for (var i = 0; i < 3; i = i + 1) { for (var j = 0; j < 3; j = j + 1) { if (j == 1) continue; n = n + 1; } } print n;`, int64(6)},
+		{`var i = 0; for (;;) { i = i + 1; if (i > 4) break; } print i;`, int64(5)},
+		{`var fns = []; for (var i = 0; i < 3; i = i + 1) { var j = i; fun f() { return j; } push(fns, f); if (j == 1) break; } print fns[1]();`, int64(1)},
+		{`fun f() { while (true) { var x = "x"; break; } return "done"; } print f();`, "done"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{"var a = 1; a += 2; print a;", int64(3)},
+		{"var a = 1; print a += 2;", int64(3)},
+		{"var a = 5; a -= 2; print a;", int64(3)},
+		{"var a = 5; a *= 2; print a;", int64(10)},
+		{"var a = 5; a /= 2; print a;", 2.5},
+		{"var a = 7; a %= 3; print a;", int64(1)},
+		{`var s = "a"; s += "b"; print s;`, "ab"},
+		{"var a = 2; a *= 1 + 2; print a;", int64(6)},
+		{"{ var a = 1; a += 2; print a; }", int64(3)},
+		{"fun f() { var a = 1; fun g() { a += 10; } g(); return a; } print f();", int64(11)},
+		{"var i = 0; i++; print i;", int64(1)},
+		{"var i = 0; print i++;", int64(0)},
+		{"var i = 0; print ++i;", int64(1)},
+		{"var i = 0; i--; print i;", int64(-1)},
+		{"var i = 0; print i--;", int64(0)},
+		{"var i = 0; print --i;", int64(-1)},
+		{"{ var i = 1.5; i++; print i; }", 2.5},
+		{"var i = 1; var j = i++ + i; print j;", int64(3)},
+		{"var i = 1; print -i++;", int64(-1)},
+		{"var n = 0; for (var i = 0; i < 5; i++) n += i; print n;", int64(10)},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestTernaryAssignment(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{"var a = true ? 1 : 2; print a;", int64(1)},
+		{"var a; var b = false ? a = 1 : 2; print a;", nil},
+		{"var a; a = false ? 1 : 2; print a;", int64(2)},
+		{"fun f(x) { return x > 0 ? \"pos\" : x < 0 ? \"neg\" : \"zero\"; } print f(0);", "zero"},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`assert true; print 1;`, int64(1)},
+		{`var x = 5; assert x > 0, "x must be positive"; print x;`, int64(5)},
+		{`try { assert 1 > 2; } catch (e) { print e.message; }`, "Assertion '1 > 2' failed at line 1."},
+		{`var x = 5; try { assert x < 0, "x is ${x}"; } catch (e) { print e.message; }`, "Assertion 'x < 0' failed at line 1: x is 5"},
+		{"try {\n  assert nil\n    or false;\n} catch (e) { print e.message; }", "Assertion 'nil\n    or false' failed at line 2."},
+		{`var n = 0; fun f() { n++; return "no"; } assert true, f(); print n;`, int64(0)},
+		{`fun f() { assert false; } try { f(); } catch (e) { print e.message; }`, "Assertion 'false' failed at line 1."},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
+
+func TestStripAsserts(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{`assert false, "stripped"; print 1;`, int64(1)},
+		{`var n = 0; fun f() { n++; return true; } assert f(); print n;`, int64(0)},
+		{`var i = 0; while (i < 3) { assert i < 0; i++; } print i;`, int64(3)},
+	}
+
+	for _, test := range tests {
+		vmachine := vm.New()
+		vmachine.StripAsserts = true
+		vmachine.Interpret(test.source)
+		if vmachine.Out != test.result {
+			t.Errorf("Incorrect result for source '%s'. Expected: %v. Got: %v.", test.source, test.result, vmachine.Out)
+		}
+	}
+}
+
+func TestTrailingComments(t *testing.T) {
+	tests := []struct {
+		source string
+		result interface{}
+	}{
+		{"var x = true; if (x) // note\n print 1;", int64(1)},
+		{"fun f() { return 2; } print f() // call\n;", int64(2)},
+		{"var a = 3; print a // identifier\n;", int64(3)},
+		{"print 4 // literal\n;", int64(4)},
+		{"print \"s\" // string\n;", "s"},
+		{"var xs = [5]; print xs[0] // subscript\n;", int64(5)},
+		{"print 7 ~/ 2 // floor division\n;", int64(3)},
+	}
+
+	for _, test := range tests {
+		RunStatementTest(t, test.source, test.result)
+	}
+}
@@ -6,50 +6,89 @@ type Type string
 
 const (
 	// Single-character tokens
-	LEFT_PAREN  = "("
-	RIGHT_PAREN = ")"
-	LEFT_BRACE  = "{"
-	RIGHT_BRACE = "}"
-	COMMA       = ","
-	DOT         = "."
-	MINUS       = "-"
-	PLUS        = "+"
-	SEMICOLON   = ";"
-	SLASH       = "/"
-	STAR        = "*"
+	LEFT_PAREN    = "("
+	RIGHT_PAREN   = ")"
+	LEFT_BRACE    = "{"
+	RIGHT_BRACE   = "}"
+	LEFT_BRACKET  = "["
+	RIGHT_BRACKET = "]"
+	COLON         = ":"
+	QUESTION      = "?"
+	COMMA         = ","
+	DOT           = "."
+	MINUS         = "-"
+	PLUS          = "+"
+	SEMICOLON     = ";"
+	SLASH         = "/"
+	STAR          = "*"
+	PERCENT       = "%"
+	AMPERSAND     = "&"
+	PIPE          = "|"
+	CARET         = "^"
+	TILDE         = "~"
 
 	// One or two character tokens
-	BANG          = "!"
-	BANG_EQUAL    = "!="
-	EQUAL         = "="
-	EQUAL_EQUAL   = "=="
-	GREATER       = ">"
-	GREATER_EQUAL = ">="
-	LESS          = "<"
-	LESS_EQUAL    = "<="
+	BANG            = "!"
+	BANG_EQUAL      = "!="
+	EQUAL           = "="
+	EQUAL_EQUAL     = "=="
+	ARROW           = "=>"
+	GREATER         = ">"
+	GREATER_EQUAL   = ">="
+	LESS            = "<"
+	LESS_EQUAL      = "<="
+	TILDE_SLASH     = "~/"
+	STAR_STAR       = "**"
+	LESS_LESS       = "<<"
+	GREATER_GREATER = ">>"
+	PLUS_EQUAL      = "+="
+	MINUS_EQUAL     = "-="
+	STAR_EQUAL      = "*="
+	SLASH_EQUAL     = "/="
+	PERCENT_EQUAL   = "%="
+	PLUS_PLUS       = "++"
+	MINUS_MINUS     = "--"
+	ELLIPSIS        = "..."
 
 	// Literals
-	IDENTIFIER = "IDENT"
-	STRING     = "STR"
-	NUMBER     = "NUM"
+	IDENTIFIER    = "IDENT"
+	STRING        = "STR"
+	INTERPOLATION = "INTERP"
+	NUMBER        = "NUM"
 
 	// Keywords
-	AND    = "and"
-	CLASS  = "class"
-	ELSE   = "else"
-	FALSE  = "false"
-	FUN    = "fun"
-	FOR    = "for"
-	IF     = "if"
-	NIL    = "nil"
-	OR     = "or"
-	PRINT  = "print"
-	RETURN = "return"
-	SUPER  = "super"
-	THIS   = "this"
-	TRUE   = "true"
-	VAR    = "var"
-	WHILE  = "while"
+	AND      = "and"
+	ASSERT   = "assert"
+	BREAK    = "break"
+	CASE     = "case"
+	CATCH    = "catch"
+	CLASS    = "class"
+	CONST    = "const"
+	CONTINUE = "continue"
+	ELSE     = "else"
+	ENUM     = "enum"
+	EXPORT   = "export"
+	FALSE    = "false"
+	FINALLY  = "finally"
+	FUN      = "fun"
+	FOR      = "for"
+	IF       = "if"
+	IMPORT   = "import"
+	IN       = "in"
+	MATCH    = "match"
+	NIL      = "nil"
+	OR       = "or"
+	PRINT    = "print"
+	RETURN   = "return"
+	SPAWN    = "spawn"
+	SUPER    = "super"
+	THIS     = "this"
+	THROW    = "throw"
+	TRUE     = "true"
+	TRY      = "try"
+	VAR      = "var"
+	WHILE    = "while"
+	YIELD    = "yield"
 
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
@@ -3,16 +3,16 @@ package vm
 import "golox/repr"
 
 type CallFrame struct {
-	Function   *repr.Function
+	Closure    *repr.Closure
 	IP         int
 	StackStart int
 }
 
-func (vm *VM) AddFrame(function *repr.Function, ip, stackStart int) {
-	vm.Frames = append(vm.Frames, &CallFrame{function, ip, stackStart})
+func (vm *VM) AddFrame(closure *repr.Closure, ip, stackStart int) {
+	vm.Fiber.Frames = append(vm.Fiber.Frames, &CallFrame{closure, ip, stackStart})
 }
 
 func (vm *VM) RemoveFrame() {
-	vm.Stack = vm.Stack[:vm.CurrFrame().StackStart]
-	vm.Frames = vm.Frames[:vm.FrameCount()-1]
+	vm.Fiber.Stack = vm.Fiber.Stack[:vm.CurrFrame().StackStart]
+	vm.Fiber.Frames = vm.Fiber.Frames[:vm.FrameCount()-1]
 }
@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"fmt"
+	"golox/repr"
+)
+
+func channelArg(name string, arg repr.Value) *repr.Channel {
+	if !arg.IsChannel() {
+		runtimeError(fmt.Sprintf("%s() expects a channel.", name))
+	}
+	return arg.AsChannel()
+}
+
+// channelNative creates a channel, unbuffered unless given a capacity.
+func channelNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("channel", argCount, 0, 1)
+	capacity := int64(0)
+	if argCount == 1 {
+		var ok bool
+		capacity, ok = args[0].ToInt()
+		if !ok || capacity < 0 {
+			runtimeError("channel() capacity must be a non-negative integer.")
+		}
+	}
+	return repr.ChannelVal(repr.NewChannel(int(capacity)))
+}
+
+// sendNative sends a value on a channel, blocking until it is received or
+// buffered.
+func (vm *VM) sendNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("send", argCount, 2, 2)
+	channel := channelArg("send", args[0])
+	value := args[1]
+	if channel.Closed {
+		runtimeError("Cannot send on a closed channel.")
+	}
+
+	if !channel.TrySend(value) {
+		vm.block("send", func(wake func(repr.Value) bool) {
+			channel.Senders = append(channel.Senders, &repr.Waiter{Value: value, Wake: wake})
+		})
+	}
+	return repr.NilVal()
+}
+
+// receiveNative receives a value from a channel, blocking until one is sent.
+// Once the channel is closed and empty, it returns nil.
+func (vm *VM) receiveNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("receive", argCount, 1, 1)
+	channel := channelArg("receive", args[0])
+
+	value, ok := channel.TryReceive()
+	if !ok {
+		vm.block("receive", func(wake func(repr.Value) bool) {
+			channel.Receivers = append(channel.Receivers, &repr.Waiter{Value: repr.NilVal(), Wake: wake})
+		})
+	}
+	return value
+}
+
+func closeNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("close", argCount, 1, 1)
+	channel := channelArg("close", args[0])
+	if channel.Closed {
+		runtimeError("Cannot close a channel twice.")
+	}
+	channel.Close()
+	return repr.NilVal()
+}
+
+// selectNative waits on several channel operations at once and performs the
+// first one that can proceed, trying them in order. Each argument is either
+// a channel to receive from, or a [channel, value] list to send value on it.
+// It returns a [index, value] list giving the position of the operation done
+// and the value received, which is nil for sends.
+func (vm *VM) selectNative(argCount int, args []repr.Value) repr.Value {
+	if argCount == 0 {
+		runtimeError("select() expects at least one channel.")
+	}
+
+	type operation struct {
+		channel *repr.Channel
+		send    bool
+		value   repr.Value
+	}
+	operations := make([]operation, argCount)
+	for i, arg := range args {
+		if arg.IsList() && len(arg.AsList().Items) == 2 && arg.AsList().Items[0].IsChannel() {
+			items := arg.AsList().Items
+			operations[i] = operation{items[0].AsChannel(), true, items[1]}
+		} else if arg.IsChannel() {
+			operations[i] = operation{arg.AsChannel(), false, repr.NilVal()}
+		} else {
+			runtimeError("select() expects channels or [channel, value] lists.")
+		}
+		if operations[i].send && operations[i].channel.Closed {
+			runtimeError("Cannot send on a closed channel.")
+		}
+	}
+
+	result := func(i int, value repr.Value) repr.Value {
+		return repr.ListVal(repr.NewList([]repr.Value{repr.IntVal(int64(i)), value}))
+	}
+
+	for i, op := range operations {
+		if op.send && op.channel.TrySend(op.value) {
+			return result(i, repr.NilVal())
+		} else if !op.send {
+			if value, ok := op.channel.TryReceive(); ok {
+				return result(i, value)
+			}
+		}
+	}
+
+	vm.block("select", func(wake func(repr.Value) bool) {
+		for i, op := range operations {
+			i := i
+			if op.send {
+				op.channel.Senders = append(op.channel.Senders, &repr.Waiter{Value: op.value, Wake: func(repr.Value) bool {
+					return wake(result(i, repr.NilVal()))
+				}})
+			} else {
+				op.channel.Receivers = append(op.channel.Receivers, &repr.Waiter{Value: repr.NilVal(), Wake: func(value repr.Value) bool {
+					return wake(result(i, value))
+				}})
+			}
+		}
+	})
+	return repr.NilVal()
+}
@@ -0,0 +1,163 @@
+package vm
+
+import (
+	"fmt"
+	"golox/repr"
+	"os"
+	"strings"
+)
+
+// exception is a Lox value being thrown, along with the stack trace at the
+// point it was thrown. It unwinds the Go stack as a panic until run recovers
+// it.
+type exception struct {
+	Value repr.Value
+	Trace string
+}
+
+// runtimeErr is raised by runtimeError and turned into an instance of Error
+// once it reaches run.
+type runtimeErr struct {
+	message string
+}
+
+// runtimeError raises a catchable Error with the given message. It can be
+// called from anywhere in the VM, including native functions.
+func runtimeError(msg string) {
+	panic(runtimeErr{msg})
+}
+
+// throw raises value as an exception.
+func (vm *VM) throw(value repr.Value) {
+	panic(vm.newException(value))
+}
+
+// newException records the current stack trace for value. Instances also
+// get it in their 'stack' field, unless they were thrown before and have
+// one already.
+func (vm *VM) newException(value repr.Value) *exception {
+	trace := vm.stackTrace()
+	if value.IsInstance() {
+		fields := value.AsInstance().Fields
+		if stack, ok := fields["stack"]; ok && stack.IsString() {
+			trace = stack.AsString()
+		} else {
+			fields["stack"] = repr.StringVal(trace)
+		}
+	}
+	return &exception{value, trace}
+}
+
+func (vm *VM) newError(message string) repr.Value {
+	instance := repr.NewInstance(vm.ErrorClass)
+	instance.Fields["message"] = repr.StringVal(message)
+	return repr.InstanceVal(instance)
+}
+
+func (vm *VM) stackTrace() string {
+	lines := []string{}
+	for i := vm.FrameCount() - 1; i >= 0; i-- {
+		frame := vm.Fiber.Frames[i]
+		function := frame.Closure.Function
+
+		ip := frame.IP - 1
+		if ip < 0 {
+			ip = 0
+		}
+		line := function.Chunk.Lines[ip]
+
+		if function.Name == "" {
+			lines = append(lines, fmt.Sprintf("[line %d] in script", line))
+		} else {
+			lines = append(lines, fmt.Sprintf("[line %d] in %s()", line, function.Name))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// run executes instructions until the frame at baseFrame returns, passing
+// exceptions to the handlers in the frames it runs. Exceptions without one
+// are reported when running the script itself, and otherwise passed on to
+// the Go code that called into the VM or resumed the generator.
+func (vm *VM) run(baseFrame int) InterpretResult {
+	vm.depth++
+	defer func() { vm.depth-- }()
+
+	for {
+		result, thrown := vm.executeUntilThrow(baseFrame)
+		if thrown == nil {
+			return result
+		}
+		if vm.catch(thrown, baseFrame) {
+			continue
+		}
+
+		if baseFrame > 0 || vm.Fiber.Generator != nil {
+			vm.Fiber.Frames = vm.Fiber.Frames[:baseFrame]
+			panic(thrown)
+		}
+		vm.reportException(thrown)
+		vm.resetStack()
+		return INTERPRET_RUNTIME_ERROR
+	}
+}
+
+func (vm *VM) executeUntilThrow(baseFrame int) (result InterpretResult, thrown *exception) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch err := r.(type) {
+			case *exception:
+				thrown = err
+			case runtimeErr:
+				thrown = vm.newException(vm.newError(err.message))
+			default:
+				panic(r)
+			}
+		}
+	}()
+
+	return vm.execute(baseFrame), nil
+}
+
+// catch unwinds the stack to the innermost handler for thrown in the frames
+// from baseFrame upwards, reporting whether there was one.
+func (vm *VM) catch(thrown *exception, baseFrame int) bool {
+	for i := vm.FrameCount() - 1; i >= baseFrame; i-- {
+		frame := vm.Fiber.Frames[i]
+		handler, ok := frame.Closure.Function.Chunk.Handler(frame.IP - 1)
+		if !ok {
+			continue
+		}
+
+		top := frame.StackStart + handler.Slots
+		vm.closeUpvalues(top)
+		vm.Fiber.Stack = vm.Fiber.Stack[:top]
+		vm.Fiber.Frames = vm.Fiber.Frames[:i+1]
+		vm.push(thrown.Value)
+		frame.IP = handler.Target
+		return true
+	}
+	return false
+}
+
+func (vm *VM) reportException(thrown *exception) {
+	value := thrown.Value
+	if value.IsInstance() {
+		instance := value.AsInstance()
+		if message, ok := instance.Fields["message"]; ok {
+			fmt.Fprintf(os.Stderr, "%s: %s\n%s\n", instance.Class.Name, message, thrown.Trace)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Uncaught exception: %s\n%s\n", value, thrown.Trace)
+}
+
+func (vm *VM) resetStack() {
+	vm.Fiber = vm.main
+	vm.Fiber.Stack = vm.Fiber.Stack[:0]
+	vm.Fiber.Frames = vm.Fiber.Frames[:0]
+	vm.Fiber.OpenUpvalues = nil
+	vm.Fiber.Waiting = ""
+	vm.ready = []*Fiber{}
+	vm.blocked = []*Fiber{}
+}
@@ -0,0 +1,201 @@
+package vm
+
+import (
+	"fmt"
+	"golox/repr"
+	"strings"
+)
+
+// Fiber is a thread of execution with its own stack and call frames. The
+// script runs in the main fiber. Every call to a generator function creates a
+// new one, as does every spawn statement.
+type Fiber struct {
+	Frames       []*CallFrame
+	Stack        []repr.Value
+	OpenUpvalues []*repr.Upvalue
+	Name         string
+	// Generator is the generator running on the fiber, or nil for the main
+	// fiber and spawned ones.
+	Generator *repr.Generator
+	started   bool
+	running   bool
+	// Waiting describes what a blocked fiber is waiting for, and is empty
+	// while it can run. cancel stops the channels it waits on from waking it.
+	Waiting string
+	cancel  func()
+}
+
+func newFiber(name string) *Fiber {
+	return &Fiber{[]*CallFrame{}, []repr.Value{}, nil, name, nil, false, false, "", nil}
+}
+
+// newGenerator replaces the callee and arguments of a call to a generator
+// function with a generator. They are moved to the generator's own fiber,
+// where the call starts once the generator is first resumed.
+func (vm *VM) newGenerator(closure *repr.Closure, argCount int) {
+	start := len(vm.Fiber.Stack) - argCount - 1
+	fiber := newFiber(closure.Function.Name)
+	fiber.Stack = append(fiber.Stack, vm.Fiber.Stack[start:]...)
+	fiber.Frames = append(fiber.Frames, &CallFrame{closure, 0, 0})
+	vm.Fiber.Stack = vm.Fiber.Stack[:start]
+
+	generator := &repr.Generator{Name: closure.Function.Name}
+	generator.Resume = func(sent repr.Value) (repr.Value, bool) {
+		return vm.resume(fiber, sent)
+	}
+	fiber.Generator = generator
+	vm.push(repr.GeneratorVal(generator))
+}
+
+// resume switches to the fiber and runs it until it yields or returns, and
+// returns the value yielded or returned. Exceptions it does not catch end
+// the generator and are rethrown in the fiber that resumed it.
+func (vm *VM) resume(fiber *Fiber, sent repr.Value) (repr.Value, bool) {
+	generator := fiber.Generator
+	if generator.Done {
+		return repr.NilVal(), false
+	}
+	if fiber.running {
+		runtimeError(fmt.Sprintf("Generator %s is already running.", generator.Name))
+	}
+
+	resumer := vm.Fiber
+	vm.Fiber = fiber
+	fiber.running = true
+	defer func() {
+		fiber.running = false
+		generator.Done = len(fiber.Frames) == 0
+		vm.Fiber = resumer
+	}()
+
+	// The first resume starts the call, with no yield to receive sent.
+	if fiber.started {
+		vm.push(sent)
+	}
+	fiber.started = true
+
+	vm.run(0)
+	return vm.pop(), vm.FrameCount() > 0
+}
+
+// generatorMethod returns the method called name of a generator as a native
+// function. next() resumes the generator, optionally sending it a value, and
+// returns the value it yields. The call that finishes the generator returns
+// the value it returns, and later calls return nil. done() reports whether
+// the generator has returned.
+func (vm *VM) generatorMethod(generator *repr.Generator, name string) repr.Value {
+	var fn repr.NativeFn
+	switch name {
+	case "next":
+		fn = func(argCount int, args []repr.Value) repr.Value {
+			checkArgCount("next", argCount, 0, 1)
+			sent := repr.NilVal()
+			if argCount == 1 {
+				sent = args[0]
+			}
+			value, _ := generator.Resume(sent)
+			return value
+		}
+	case "done":
+		fn = func(argCount int, args []repr.Value) repr.Value {
+			checkArgCount("done", argCount, 0, 0)
+			return repr.BoolVal(generator.Done)
+		}
+	default:
+		runtimeError(fmt.Sprintf("Undefined property '%s'.", name))
+	}
+	return repr.NativeVal(&repr.Native{Name: name, Fn: fn})
+}
+
+// spawn moves the callee and arguments of a call to a new fiber, which runs
+// the call once the fibers before it in the ready queue have had their turn.
+func (vm *VM) spawn(argCount int) {
+	start := len(vm.Fiber.Stack) - argCount - 1
+	callee := vm.Fiber.Stack[start]
+	if !callee.IsClosure() && !callee.IsBoundMethod() {
+		runtimeError("Can only spawn Lox functions.")
+	}
+
+	var name string
+	if callee.IsClosure() {
+		name = callee.AsClosure().Function.Name
+	} else {
+		name = callee.AsBoundMethod().Method.Function.Name
+	}
+
+	fiber := newFiber(name)
+	fiber.Stack = append(fiber.Stack, vm.Fiber.Stack[start:]...)
+	vm.Fiber.Stack = vm.Fiber.Stack[:start]
+
+	spawner := vm.Fiber
+	vm.Fiber = fiber
+	defer func() { vm.Fiber = spawner }()
+
+	vm.callValue(callee, argCount)
+	// Generator functions return right away, leaving nothing to run.
+	if len(fiber.Frames) > 0 {
+		vm.ready = append(vm.ready, fiber)
+	}
+}
+
+// block suspends the current fiber and switches to the next ready one. wait
+// registers the fiber with the channels it waits on, giving them the function
+// that wakes it up with the value its pending call evaluates to. Only the
+// outermost run switches fibers, so callbacks and generators cannot block.
+func (vm *VM) block(waiting string, wait func(wake func(repr.Value) bool)) {
+	if vm.depth > 1 {
+		runtimeError(fmt.Sprintf("Cannot %s inside a callback or a generator.", waiting))
+	}
+
+	fiber := vm.Fiber
+	woken := false
+	fiber.Waiting = waiting
+	fiber.cancel = func() { woken = true }
+	vm.blocked = append(vm.blocked, fiber)
+
+	wait(func(value repr.Value) bool {
+		if woken {
+			return false
+		}
+		woken = true
+		vm.unblock(fiber)
+		fiber.Stack = append(fiber.Stack, value)
+		vm.ready = append(vm.ready, fiber)
+		return true
+	})
+	vm.schedule()
+}
+
+func (vm *VM) unblock(fiber *Fiber) {
+	fiber.Waiting = ""
+	fiber.cancel = nil
+	for i, blocked := range vm.blocked {
+		if blocked == fiber {
+			vm.blocked = append(vm.blocked[:i], vm.blocked[i+1:]...)
+			break
+		}
+	}
+}
+
+// schedule switches to the next fiber ready to run. Without one, every fiber
+// left is blocked for good, and the main fiber stops waiting to raise an
+// error listing them.
+func (vm *VM) schedule() {
+	if len(vm.ready) > 0 {
+		vm.Fiber = vm.ready[0]
+		vm.ready = vm.ready[1:]
+		return
+	}
+
+	lines := []string{"Deadlock: every fiber is blocked."}
+	for _, fiber := range vm.blocked {
+		frame := fiber.Frames[len(fiber.Frames)-1]
+		line := frame.Closure.Function.Chunk.Lines[frame.IP-1]
+		lines = append(lines, fmt.Sprintf("  %s: waiting to %s at line %d", fiber.Name, fiber.Waiting, line))
+	}
+
+	vm.main.cancel()
+	vm.unblock(vm.main)
+	vm.Fiber = vm.main
+	runtimeError(strings.Join(lines, "\n"))
+}
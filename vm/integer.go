@@ -0,0 +1,98 @@
+package vm
+
+import (
+	"golox/repr"
+	"math"
+)
+
+// intOp applies an arithmetic or comparison operator to two integers.
+// Addition, subtraction, multiplication and exponentiation raise a runtime
+// error instead of wrapping around on overflow. Division with '/' always
+// produces a float.
+func (vm *VM) intOp(op byte, a, b int64) repr.Value {
+	switch op {
+	case repr.OP_GREATER:
+		return repr.BoolVal(a > b)
+	case repr.OP_LESS:
+		return repr.BoolVal(a < b)
+	case repr.OP_ADD:
+		return repr.IntVal(vm.addInt(a, b))
+	case repr.OP_SUBTRACT:
+		result := a - b
+		if (b > 0 && result > a) || (b < 0 && result < a) {
+			runtimeError("Integer overflow.")
+		}
+		return repr.IntVal(result)
+	case repr.OP_MULTIPLY:
+		return repr.IntVal(vm.multiplyInt(a, b))
+	case repr.OP_DIVIDE:
+		return repr.NumberVal(float64(a) / float64(b))
+	case repr.OP_FLOOR_DIVIDE:
+		if b == 0 {
+			runtimeError("Division by zero.")
+		}
+		if a == math.MinInt64 && b == -1 {
+			runtimeError("Integer overflow.")
+		}
+		quotient := a / b
+		if a%b != 0 && (a < 0) != (b < 0) {
+			quotient--
+		}
+		return repr.IntVal(quotient)
+	case repr.OP_MODULO:
+		if b == 0 {
+			runtimeError("Division by zero.")
+		}
+		remainder := a % b
+		if remainder != 0 && (remainder < 0) != (b < 0) {
+			remainder += b
+		}
+		return repr.IntVal(remainder)
+	case repr.OP_POWER:
+		if b < 0 {
+			return repr.NumberVal(math.Pow(float64(a), float64(b)))
+		}
+		result := int64(1)
+		for ; b > 0; b >>= 1 {
+			if b&1 == 1 {
+				result = vm.multiplyInt(result, a)
+			}
+			if b > 1 {
+				a = vm.multiplyInt(a, a)
+			}
+		}
+		return repr.IntVal(result)
+	default:
+		// Unreachable
+		return repr.NilVal()
+	}
+}
+
+func (vm *VM) addInt(a, b int64) int64 {
+	result := a + b
+	if (b > 0 && result < a) || (b < 0 && result > a) {
+		runtimeError("Integer overflow.")
+	}
+	return result
+}
+
+func (vm *VM) multiplyInt(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	result := a * b
+	if result/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		runtimeError("Integer overflow.")
+	}
+	return result
+}
+
+// intOrFloatLess compares two numbers without losing precision when both are
+// integers.
+func (vm *VM) intOrFloatLess(a, b repr.Value) bool {
+	if a.IsInt() && b.IsInt() {
+		return a.AsInt() < b.AsInt()
+	}
+	return a.ToFloat() < b.ToFloat()
+}
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"fmt"
+	"golox/repr"
+)
+
+// iterate returns an iterator over the values of a for-in loop.
+//
+// Lists, tuples, maps (by key), strings (by character) and enums (by member)
+// are iterated natively, as are generators and the iterators returned by
+// natives such as range(). An instance is iterable when its class follows the
+// iterator protocol:
+//
+//   - iter() returns the iterator to use, which may be any iterable value.
+//   - Otherwise the instance is its own iterator, and must have hasNext(),
+//     telling whether there are values left, and next(), returning the next
+//     one.
+func (vm *VM) iterate(value repr.Value) repr.Value {
+	switch value.Type {
+	case repr.VAL_ITERATOR:
+		return value
+	case repr.VAL_GENERATOR:
+		generator := value.AsGenerator()
+		return repr.IteratorVal(repr.NewIterator("generator", func() (repr.Value, bool) {
+			return generator.Resume(repr.NilVal())
+		}))
+	case repr.VAL_LIST:
+		// The length is checked on every step, so items pushed during the
+		// loop are visited too.
+		items := value.AsList()
+		i := 0
+		return repr.IteratorVal(repr.NewIterator("list", func() (repr.Value, bool) {
+			if i >= len(items.Items) {
+				return repr.NilVal(), false
+			}
+			i++
+			return items.Items[i-1], true
+		}))
+	case repr.VAL_TUPLE:
+		return sliceIterator("tuple", value.AsTuple().Items)
+	case repr.VAL_ENUM:
+		members := value.AsEnum().Members
+		values := make([]repr.Value, len(members))
+		for i, member := range members {
+			values[i] = repr.EnumMemberVal(member)
+		}
+		return sliceIterator("enum", values)
+	case repr.VAL_MAP:
+		keys := append([]repr.Value{}, value.AsMap().Keys...)
+		return sliceIterator("map", keys)
+	case repr.VAL_STRING:
+		runes := []rune(value.AsString())
+		chars := make([]repr.Value, len(runes))
+		for i, r := range runes {
+			chars[i] = repr.StringVal(string(r))
+		}
+		return sliceIterator("string", chars)
+	case repr.VAL_INSTANCE:
+		class := value.AsInstance().Class
+		if _, ok := class.Methods["iter"]; ok {
+			iterable := vm.callMethod(value, "iter")
+			if !iterable.Equals(value) {
+				return vm.iterate(iterable)
+			}
+		}
+
+		_, hasNext := class.Methods["hasNext"]
+		_, next := class.Methods["next"]
+		if !hasNext || !next {
+			runtimeError(fmt.Sprintf("%s instance is not iterable.", class.Name))
+		}
+		return repr.IteratorVal(repr.NewIterator(class.Name, func() (repr.Value, bool) {
+			if vm.isFalsey(vm.callMethod(value, "hasNext")) {
+				return repr.NilVal(), false
+			}
+			return vm.callMethod(value, "next"), true
+		}))
+	default:
+		runtimeError(fmt.Sprintf("Cannot iterate over %s.", value))
+		return repr.NilVal()
+	}
+}
+
+func sliceIterator(name string, values []repr.Value) repr.Value {
+	i := 0
+	return repr.IteratorVal(repr.NewIterator(name, func() (repr.Value, bool) {
+		if i >= len(values) {
+			return repr.NilVal(), false
+		}
+		i++
+		return values[i-1], true
+	}))
+}
+
+// callMethod calls the method called name on receiver without arguments.
+func (vm *VM) callMethod(receiver repr.Value, name string) repr.Value {
+	method := receiver.AsInstance().Class.Methods[name]
+	return vm.callFunction(repr.BoundMethodVal(repr.NewBoundMethod(receiver, method)))
+}
@@ -0,0 +1,87 @@
+package vm
+
+import (
+	"fmt"
+	"golox/parser"
+	"golox/repr"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// importModule pushes the module at path. The first import of a module
+// caches it and calls its script with the module in slot zero, which the
+// script returns once it has run.
+func (vm *VM) importModule(path string) {
+	resolved, ok := vm.resolveModule(path)
+	if !ok {
+		runtimeError(fmt.Sprintf("Cannot find module '%s'.", path))
+	}
+	if module, ok := vm.Modules[resolved]; ok {
+		vm.push(repr.ModuleVal(module))
+		return
+	}
+
+	source, err := ioutil.ReadFile(resolved)
+	if err != nil {
+		runtimeError(fmt.Sprintf("Cannot read module '%s'.", path))
+	}
+
+	// An empty script compiles to no function at all.
+	p := parser.New(string(source))
+	p.StripAsserts = vm.StripAsserts
+	function := p.Compile()
+	var exports []string
+	if function != nil {
+		exports = function.Exports
+	}
+
+	name := strings.TrimSuffix(filepath.Base(resolved), filepath.Ext(resolved))
+	module := repr.NewModule(name, resolved, make(map[string]repr.Value), exports)
+	vm.Modules[resolved] = module
+
+	vm.push(repr.ModuleVal(module))
+	if function != nil {
+		vm.AddFrame(repr.NewClosure(function, module), 0, len(vm.Fiber.Stack)-1)
+	}
+}
+
+// resolveModule finds the file imported as path, first relative to the
+// importing script and then in each directory of the search path.
+func (vm *VM) resolveModule(path string) (string, bool) {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		dir := "."
+		if importer := vm.CurrFrame().Closure.Module.Path; importer != "" {
+			dir = filepath.Dir(importer)
+		}
+
+		candidates = []string{filepath.Join(dir, path)}
+		for _, searchDir := range vm.SearchPath {
+			candidates = append(candidates, filepath.Join(searchDir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			if absPath, err := filepath.Abs(candidate); err == nil {
+				return absPath, true
+			}
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func (vm *VM) moduleMember(module *repr.Module, name string) repr.Value {
+	if !module.Exports[name] {
+		runtimeError(fmt.Sprintf("Module '%s' does not export '%s'.", module.Name, name))
+	}
+
+	value, ok := module.Globals[name]
+	if !ok {
+		runtimeError(fmt.Sprintf("Module '%s' has not defined '%s' yet.", module.Name, name))
+	}
+	return value
+}
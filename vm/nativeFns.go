@@ -1,18 +1,301 @@
 package vm
 
 import (
+	"fmt"
 	"golox/repr"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 func (vm *VM) defineNative(name string, nativeFn repr.NativeFn) {
-	vm.Globals[name] = repr.NativeVal(nativeFn)
+	vm.Builtins[name] = repr.NativeVal(&repr.Native{Name: name, Fn: nativeFn})
 }
 
 func (vm *VM) initNatives() {
 	vm.defineNative("clock", clockNative)
+
+	vm.defineNative("len", lenNative)
+	vm.defineNative("push", pushNative)
+	vm.defineNative("pop", popNative)
+	vm.defineNative("insert", vm.insertNative)
+	vm.defineNative("remove", vm.removeNative)
+	vm.defineNative("sort", vm.sortNative)
+	vm.defineNative("reverse", reverseNative)
+
+	vm.defineNative("range", rangeNative)
+
+	vm.defineNative("int", intNative)
+	vm.defineNative("float", floatNative)
+
+	vm.defineNative("keys", keysNative)
+	vm.defineNative("values", valuesNative)
+	vm.defineNative("has", vm.hasNative)
+	vm.defineNative("delete", vm.deleteNative)
+
+	vm.defineNative("channel", channelNative)
+	vm.defineNative("send", vm.sendNative)
+	vm.defineNative("receive", vm.receiveNative)
+	vm.defineNative("close", closeNative)
+	vm.defineNative("select", vm.selectNative)
+}
+
+func checkArgCount(name string, argCount, min, max int) {
+	if argCount < min || argCount > max {
+		expected := fmt.Sprintf("%d", min)
+		if min != max {
+			expected = fmt.Sprintf("%d to %d", min, max)
+		}
+		runtimeError(fmt.Sprintf("%s() expected %s arguments but got %d.", name, expected, argCount))
+	}
+}
+
+func listArg(name string, arg repr.Value) *repr.List {
+	if !arg.IsList() {
+		runtimeError(fmt.Sprintf("%s() expects a list.", name))
+	}
+	return arg.AsList()
+}
+
+func mapArg(name string, arg repr.Value) *repr.Map {
+	if !arg.IsMap() {
+		runtimeError(fmt.Sprintf("%s() expects a map.", name))
+	}
+	return arg.AsMap()
 }
 
 func clockNative(argCount int, args []repr.Value) repr.Value {
 	return repr.NumberVal(float64(time.Now().Unix()))
 }
+
+func lenNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("len", argCount, 1, 1)
+	switch args[0].Type {
+	case repr.VAL_LIST:
+		return repr.IntVal(int64(len(args[0].AsList().Items)))
+	case repr.VAL_TUPLE:
+		return repr.IntVal(int64(len(args[0].AsTuple().Items)))
+	case repr.VAL_ENUM:
+		return repr.IntVal(int64(len(args[0].AsEnum().Members)))
+	case repr.VAL_MAP:
+		return repr.IntVal(int64(args[0].AsMap().Len()))
+	case repr.VAL_STRING:
+		return repr.IntVal(int64(len([]rune(args[0].AsString()))))
+	default:
+		runtimeError("len() expects a list, a tuple, a map, a string or an enum.")
+		return repr.NilVal()
+	}
+}
+
+func pushNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("push", argCount, 2, 2)
+	list := listArg("push", args[0])
+	list.Items = append(list.Items, args[1])
+	return repr.NilVal()
+}
+
+func popNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("pop", argCount, 1, 1)
+	list := listArg("pop", args[0])
+	if len(list.Items) == 0 {
+		runtimeError("Cannot pop from an empty list.")
+	}
+
+	last := list.Items[len(list.Items)-1]
+	list.Items = list.Items[:len(list.Items)-1]
+	return last
+}
+
+func (vm *VM) insertNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("insert", argCount, 3, 3)
+	list := listArg("insert", args[0])
+
+	// Inserting at the length of the list appends to it.
+	i := len(list.Items)
+	if index, ok := args[1].ToInt(); !ok || index != int64(i) {
+		i = vm.index(args[1], len(list.Items))
+	}
+
+	list.Items = append(list.Items, repr.NilVal())
+	copy(list.Items[i+1:], list.Items[i:])
+	list.Items[i] = args[2]
+	return repr.NilVal()
+}
+
+func (vm *VM) removeNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("remove", argCount, 2, 2)
+	list := listArg("remove", args[0])
+	i := vm.index(args[1], len(list.Items))
+
+	removed := list.Items[i]
+	list.Items = append(list.Items[:i], list.Items[i+1:]...)
+	return removed
+}
+
+// sortNative sorts a list in place. Without a comparator, the list must hold
+// only numbers or only strings. A comparator is called with two items and
+// returns a negative number when the first should come before the second.
+func (vm *VM) sortNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("sort", argCount, 1, 2)
+	list := listArg("sort", args[0])
+	items := list.Items
+
+	var less func(i, j int) bool
+	if argCount == 2 {
+		comparator := args[1]
+		less = func(i, j int) bool {
+			result := vm.callFunction(comparator, items[i], items[j])
+			if !result.IsNumeric() {
+				runtimeError("sort() comparator must return a number.")
+			}
+			return result.ToFloat() < 0
+		}
+	} else {
+		for _, item := range items {
+			if item.IsNumeric() != items[0].IsNumeric() || !(item.IsNumeric() || item.IsString()) {
+				runtimeError("sort() without a comparator expects only numbers or only strings.")
+			}
+		}
+		less = func(i, j int) bool {
+			if items[i].IsNumeric() {
+				return vm.intOrFloatLess(items[i], items[j])
+			}
+			return items[i].AsString() < items[j].AsString()
+		}
+	}
+
+	sort.SliceStable(items, less)
+	return repr.NilVal()
+}
+
+func reverseNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("reverse", argCount, 1, 1)
+	items := listArg("reverse", args[0]).Items
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return repr.NilVal()
+}
+
+// rangeNative returns an iterator counting from start up to, but excluding,
+// end. The range is made of integers unless one of its bounds or its step is
+// a float.
+func rangeNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("range", argCount, 1, 3)
+	for _, arg := range args {
+		if !arg.IsNumeric() {
+			runtimeError("range() expects numbers.")
+		}
+	}
+
+	start, end, step := repr.IntVal(0), args[0], repr.IntVal(1)
+	if argCount > 1 {
+		start, end = args[0], args[1]
+	}
+	if argCount > 2 {
+		step = args[2]
+	}
+	if step.ToFloat() == 0 {
+		runtimeError("range() step cannot be zero.")
+	}
+
+	if start.IsInt() && end.IsInt() && step.IsInt() {
+		i, stop, by := start.AsInt(), end.AsInt(), step.AsInt()
+		return repr.IteratorVal(repr.NewIterator("range", func() (repr.Value, bool) {
+			if (by > 0 && i >= stop) || (by < 0 && i <= stop) {
+				return repr.NilVal(), false
+			}
+			i += by
+			return repr.IntVal(i - by), true
+		}))
+	}
+
+	// Each value is computed from the start to avoid accumulating rounding
+	// errors.
+	first, stop, by := start.ToFloat(), end.ToFloat(), step.ToFloat()
+	n := 0
+	return repr.IteratorVal(repr.NewIterator("range", func() (repr.Value, bool) {
+		x := first + float64(n)*by
+		if (by > 0 && x >= stop) || (by < 0 && x <= stop) {
+			return repr.NilVal(), false
+		}
+		n++
+		return repr.NumberVal(x), true
+	}))
+}
+
+// intNative converts a number or a numeric string to an integer. Floats are
+// truncated toward zero.
+func intNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("int", argCount, 1, 1)
+	switch arg := args[0]; arg.Type {
+	case repr.VAL_INT:
+		return arg
+	case repr.VAL_NUMBER:
+		num := math.Trunc(arg.AsNumber())
+		if math.IsNaN(num) || num < math.MinInt64 || num >= math.MaxInt64 {
+			runtimeError(fmt.Sprintf("Cannot convert %s to an integer.", arg))
+		}
+		return repr.IntVal(int64(num))
+	case repr.VAL_STRING:
+		i, err := strconv.ParseInt(strings.TrimSpace(arg.AsString()), 10, 64)
+		if err != nil {
+			runtimeError(fmt.Sprintf("Cannot convert \"%s\" to an integer.", arg))
+		}
+		return repr.IntVal(i)
+	default:
+		runtimeError("int() expects a number or a string.")
+		return repr.NilVal()
+	}
+}
+
+func floatNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("float", argCount, 1, 1)
+	switch arg := args[0]; arg.Type {
+	case repr.VAL_INT, repr.VAL_NUMBER:
+		return repr.NumberVal(arg.ToFloat())
+	case repr.VAL_STRING:
+		num, err := strconv.ParseFloat(strings.TrimSpace(arg.AsString()), 64)
+		if err != nil {
+			runtimeError(fmt.Sprintf("Cannot convert \"%s\" to a float.", arg))
+		}
+		return repr.NumberVal(num)
+	default:
+		runtimeError("float() expects a number or a string.")
+		return repr.NilVal()
+	}
+}
+
+func keysNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("keys", argCount, 1, 1)
+	m := mapArg("keys", args[0])
+
+	keys := make([]repr.Value, len(m.Keys))
+	copy(keys, m.Keys)
+	return repr.ListVal(repr.NewList(keys))
+}
+
+func valuesNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("values", argCount, 1, 1)
+	m := mapArg("values", args[0])
+
+	values := make([]repr.Value, len(m.Keys))
+	for i, key := range m.Keys {
+		values[i], _ = m.Get(key)
+	}
+	return repr.ListVal(repr.NewList(values))
+}
+
+func (vm *VM) hasNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("has", argCount, 2, 2)
+	_, ok := mapArg("has", args[0]).Get(vm.mapKey(args[1]))
+	return repr.BoolVal(ok)
+}
+
+// deleteNative removes a key from a map and reports whether it was present.
+func (vm *VM) deleteNative(argCount int, args []repr.Value) repr.Value {
+	checkArgCount("delete", argCount, 2, 2)
+	return repr.BoolVal(mapArg("delete", args[0]).Delete(vm.mapKey(args[1])))
+}
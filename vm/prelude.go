@@ -0,0 +1,23 @@
+package vm
+
+import (
+	"golox/parser"
+	"golox/repr"
+)
+
+// prelude is Lox code run before every script to define the built-in
+// classes.
+const prelude = `
+class Error {
+	init(message) {
+		this.message = message;
+	}
+}
+`
+
+// initPrelude runs the prelude with the builtins as its globals.
+func (vm *VM) initPrelude() {
+	module := repr.NewModule("prelude", "", vm.Builtins, nil)
+	vm.runFunction(parser.New(prelude).Compile(), module)
+	vm.ErrorClass = vm.Builtins["Error"].AsClass()
+}
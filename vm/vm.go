@@ -2,9 +2,12 @@ package vm
 
 import (
 	"fmt"
-	"golox/loxerror"
 	"golox/parser"
 	"golox/repr"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 )
 
 type InterpretResult byte
@@ -16,23 +19,77 @@ const (
 )
 
 type VM struct {
-	Frames  []*CallFrame
-	Stack   []repr.Value
+	// Fiber is the fiber currently running, which is the main one unless a
+	// generator is being resumed.
+	Fiber *Fiber
+	// Globals holds the globals of the main script, which persist across
+	// calls to Interpret. Imported modules have their own, and all of them
+	// fall back to Builtins.
 	Globals map[string]repr.Value
 	Out     interface{}
+	// ErrorClass is the class of the errors raised by the VM itself.
+	ErrorClass *repr.Class
+	Builtins   map[string]repr.Value
+	// Modules caches imported modules by absolute path.
+	Modules map[string]*repr.Module
+	// SearchPath lists the directories searched for modules that are not
+	// found relative to the importing script.
+	SearchPath []string
+	// StripAsserts compiles scripts and modules without their asserts.
+	StripAsserts bool
+	// script is the module of the main script, created on the first call to
+	// Interpret.
+	script *repr.Module
+	// main is the fiber running the script. The other fibers are either
+	// ready to run or blocked on a channel.
+	main    *Fiber
+	ready   []*Fiber
+	blocked []*Fiber
+	// depth counts the calls to run in progress. Only the outermost one can
+	// switch between fibers.
+	depth int
 }
 
 func New() *VM {
+	main := newFiber("main")
 	return &VM{
-		[]*CallFrame{},
-		[]repr.Value{},
+		main,
 		make(map[string]repr.Value),
 		nil,
+		nil,
+		make(map[string]repr.Value),
+		make(map[string]*repr.Module),
+		[]string{},
+		false,
+		nil,
+		main,
+		[]*Fiber{},
+		[]*Fiber{},
+		0,
 	}
 }
 
 func (vm *VM) Interpret(source string) InterpretResult {
+	return vm.interpret(source, "")
+}
+
+// InterpretFile runs the script at path, resolving its imports relative to
+// the directory containing it.
+func (vm *VM) InterpretFile(path string) InterpretResult {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return INTERPRET_COMPILE_ERROR
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	return vm.interpret(string(source), path)
+}
+
+func (vm *VM) interpret(source, path string) InterpretResult {
 	p := parser.New(source)
+	p.StripAsserts = vm.StripAsserts
 
 	mainFunc := p.Compile()
 
@@ -40,105 +97,568 @@ func (vm *VM) Interpret(source string) InterpretResult {
 		return INTERPRET_COMPILE_ERROR
 	}
 	vm.initNatives()
+	vm.initPrelude()
+	// Fibers left over from a previous script never run again.
+	vm.ready = []*Fiber{}
+	vm.blocked = []*Fiber{}
 
-	funcValue := repr.FunctionVal(mainFunc)
-	vm.push(funcValue)
-	vm.callValue(funcValue, 0)
-	return vm.run()
+	if vm.script == nil {
+		vm.script = repr.NewModule("script", path, vm.Globals, nil)
+	}
+	vm.script.Path = path
+
+	return vm.runFunction(mainFunc, vm.script)
 }
 
-func (vm *VM) FrameCount() int {
-	return len(vm.Frames)
+// runFunction runs a compiled script to completion in the given module.
+func (vm *VM) runFunction(function *repr.Function, module *repr.Module) InterpretResult {
+	closureValue := repr.ClosureVal(repr.NewClosure(function, module))
+	vm.push(closureValue)
+	vm.callValue(closureValue, 0)
+	return vm.run(0)
 }
 
-func (vm *VM) CurrFrame() *CallFrame {
-	return vm.Frames[vm.FrameCount()-1]
+func (vm *VM) FrameCount() int {
+	return len(vm.Fiber.Frames)
 }
 
-/* TODO add runtimeError handling.
-func (vm *VM) runtimeError(msg string) {
-	for i := vm.FrameCount() - 1; i >= 0; i-- {
-		frame := vm.Frames[i]
-		frameFunc := frame.Function
-		if frameFunc.Name == "" {
-			fmt.Fprintf(os.Stderr, "script\n")
-		} else {
-			fmt.Fprintf(os.Stderr, "%s()\n", frameFunc.Name)
-		}
-	}
+func (vm *VM) CurrFrame() *CallFrame {
+	return vm.Fiber.Frames[vm.FrameCount()-1]
 }
-*/
 
 func (vm *VM) push(value repr.Value) {
-	vm.Stack = append(vm.Stack, value)
+	vm.Fiber.Stack = append(vm.Fiber.Stack, value)
 }
 
 func (vm *VM) pop() repr.Value {
-	retValue := vm.Stack[len(vm.Stack)-1]
-	vm.Stack = vm.Stack[:len(vm.Stack)-1]
+	retValue := vm.Fiber.Stack[len(vm.Fiber.Stack)-1]
+	vm.Fiber.Stack = vm.Fiber.Stack[:len(vm.Fiber.Stack)-1]
 	return retValue
 }
 
 func (vm *VM) peek(distance int) repr.Value {
-	return vm.Stack[len(vm.Stack)-1-distance]
+	return vm.Fiber.Stack[len(vm.Fiber.Stack)-1-distance]
 }
 
-func (vm *VM) call(calledFunc *repr.Function, argCount int) bool {
-	if argCount != calledFunc.Arity {
-		loxerror.Error(-1, fmt.Sprintf("Expected %d arguments but got %d.", calledFunc.Arity, argCount))
-		return false
+func (vm *VM) call(closure *repr.Closure, argCount int) {
+	function := closure.Function
+	fixed := function.Arity
+	if function.Variadic {
+		fixed--
+	}
+
+	required := fixed - function.Defaults
+	if function.Variadic && argCount < required {
+		runtimeError(fmt.Sprintf("Expected at least %d arguments but got %d.", required, argCount))
+	} else if !function.Variadic && (argCount < required || argCount > fixed) {
+		if function.Defaults == 0 {
+			runtimeError(fmt.Sprintf("Expected %d arguments but got %d.", fixed, argCount))
+		} else {
+			runtimeError(fmt.Sprintf("Expected %d to %d arguments but got %d.", required, fixed, argCount))
+		}
 	}
-	vm.AddFrame(calledFunc, 0, len(vm.Stack)-argCount-1)
 
-	return true
+	// Omitted arguments are filled in by the function's prologue.
+	for ; argCount < fixed; argCount++ {
+		vm.push(repr.AbsentVal())
+	}
+
+	if function.Variadic {
+		surplus := len(vm.Fiber.Stack) - (argCount - fixed)
+		rest := make([]repr.Value, argCount-fixed)
+		copy(rest, vm.Fiber.Stack[surplus:])
+		vm.Fiber.Stack = vm.Fiber.Stack[:surplus]
+		vm.push(repr.ListVal(repr.NewList(rest)))
+		argCount = function.Arity
+	}
+
+	if function.Generator {
+		vm.newGenerator(closure, argCount)
+		return
+	}
+	vm.AddFrame(closure, 0, len(vm.Fiber.Stack)-argCount-1)
 }
 
-func (vm *VM) callValue(callee repr.Value, argCount int) bool {
-	if callee.IsFunction() {
-		return vm.call(callee.AsFunction(), argCount)
+func (vm *VM) callValue(callee repr.Value, argCount int) {
+	if callee.IsClosure() {
+		vm.call(callee.AsClosure(), argCount)
+	} else if callee.IsBoundMethod() {
+		bound := callee.AsBoundMethod()
+		vm.Fiber.Stack[len(vm.Fiber.Stack)-argCount-1] = bound.Receiver
+		vm.call(bound.Method, argCount)
+	} else if callee.IsClass() {
+		class := callee.AsClass()
+		vm.Fiber.Stack[len(vm.Fiber.Stack)-argCount-1] = repr.InstanceVal(repr.NewInstance(class))
+		if initializer, ok := class.Methods["init"]; ok {
+			vm.call(initializer, argCount)
+		} else if argCount != 0 {
+			runtimeError(fmt.Sprintf("Expected 0 arguments but got %d.", argCount))
+		}
 	} else if callee.IsNative() {
 		native := callee.AsNative()
-		result := native(argCount, vm.Stack[len(vm.Stack)-argCount-1:])
-		vm.push(result)
-		return true
+		fiber := vm.Fiber
+		result := native.Fn(argCount, fiber.Stack[len(fiber.Stack)-argCount:])
+		fiber.Stack = fiber.Stack[:len(fiber.Stack)-argCount-1]
+		// A native that blocked the fiber leaves its result to the fiber
+		// waking it up.
+		if fiber.Waiting == "" {
+			fiber.Stack = append(fiber.Stack, result)
+		}
 	} else {
-		loxerror.Error(-1, "Can only call functions and classes.")
+		runtimeError("Can only call functions and classes.")
+	}
+}
+
+// callNamed calls callee with argCount arguments, the last len(names) of which
+// are passed by name. The arguments are rearranged into parameter order, with
+// omitted ones marked absent, before making an ordinary call.
+func (vm *VM) callNamed(callee repr.Value, argCount int, names []repr.Value) {
+	var function *repr.Function
+	if callee.IsClosure() {
+		function = callee.AsClosure().Function
+	} else if callee.IsBoundMethod() {
+		function = callee.AsBoundMethod().Method.Function
+	} else if callee.IsClass() {
+		if initializer, ok := callee.AsClass().Methods["init"]; ok {
+			function = initializer.Function
+		}
+	}
+	if function == nil {
+		runtimeError("Named arguments can only be passed to Lox functions.")
+	}
+
+	fixed := function.Arity
+	if function.Variadic {
+		fixed--
+	}
+
+	start := len(vm.Fiber.Stack) - argCount
+	positional := argCount - len(names)
+	if positional > fixed && !function.Variadic {
+		runtimeError(fmt.Sprintf("Expected at most %d positional arguments but got %d.", fixed, positional))
+	}
+
+	// Surplus positional arguments are kept after the fixed ones for call to
+	// collect into the rest parameter.
+	args := make([]repr.Value, fixed)
+	for i := range args {
+		args[i] = repr.AbsentVal()
+	}
+	copy(args, vm.Fiber.Stack[start:start+positional])
+	if positional > fixed {
+		args = append(args, vm.Fiber.Stack[start+fixed:start+positional]...)
+	}
+
+	for i, name := range names {
+		param := paramIndex(function, name.AsString())
+		if param == -1 {
+			runtimeError(fmt.Sprintf("%s() has no parameter named '%s'.", function.Name, name.AsString()))
+		}
+		if param >= fixed {
+			runtimeError(fmt.Sprintf("Cannot pass rest parameter '%s' by name.", name.AsString()))
+		}
+		if !args[param].IsAbsent() {
+			runtimeError(fmt.Sprintf("Got multiple values for parameter '%s'.", name.AsString()))
+		}
+		args[param] = vm.Fiber.Stack[start+positional+i]
+	}
+
+	for i := 0; i < fixed-function.Defaults; i++ {
+		if args[i].IsAbsent() {
+			runtimeError(fmt.Sprintf("Missing argument for parameter '%s'.", function.Params[i]))
+		}
+	}
+
+	vm.Fiber.Stack = append(vm.Fiber.Stack[:start], args...)
+	vm.callValue(callee, len(args))
+}
+
+// spreadArgs expands the list arguments at the given positions among the top
+// argCount values into separate arguments, returning the new argument count.
+func (vm *VM) spreadArgs(argCount int, spreads []repr.Value) int {
+	start := len(vm.Fiber.Stack) - argCount
+	args := make([]repr.Value, 0, argCount)
+	next := 0
+	for i, arg := range vm.Fiber.Stack[start:] {
+		if next < len(spreads) && int(spreads[next].AsInt()) == i {
+			next++
+			switch arg.Type {
+			case repr.VAL_LIST:
+				args = append(args, arg.AsList().Items...)
+			case repr.VAL_TUPLE:
+				args = append(args, arg.AsTuple().Items...)
+			default:
+				runtimeError("Can only spread a list or a tuple.")
+			}
+		} else {
+			args = append(args, arg)
+		}
+	}
+
+	vm.Fiber.Stack = append(vm.Fiber.Stack[:start], args...)
+	return len(args)
+}
+
+func paramIndex(function *repr.Function, name string) int {
+	for i, param := range function.Params {
+		if param == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// callFunction calls a Lox value from Go code, such as a native function
+// taking a callback, and runs it to completion.
+func (vm *VM) callFunction(callee repr.Value, args ...repr.Value) repr.Value {
+	baseFrame := vm.FrameCount()
+	vm.push(callee)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+
+	vm.callValue(callee, len(args))
+	if vm.FrameCount() > baseFrame {
+		vm.run(baseFrame)
+	}
+	return vm.pop()
+}
+
+func (vm *VM) invokeFromClass(class *repr.Class, name string, argCount int) {
+	method, ok := class.Methods[name]
+	if !ok {
+		runtimeError(fmt.Sprintf("Undefined property '%s'.", name))
+	}
+	vm.call(method, argCount)
+}
+
+func (vm *VM) invoke(name string, argCount int) {
+	receiver := vm.peek(argCount)
+	if vm.hasMembers(receiver) {
+		value := vm.member(receiver, name)
+		vm.Fiber.Stack[len(vm.Fiber.Stack)-argCount-1] = value
+		vm.callValue(value, argCount)
+		return
+	}
+	if !receiver.IsInstance() {
+		runtimeError("Only instances have methods.")
+	}
+
+	instance := receiver.AsInstance()
+	// A field holding a function shadows a method of the same name.
+	if value, ok := instance.Fields[name]; ok {
+		vm.Fiber.Stack[len(vm.Fiber.Stack)-argCount-1] = value
+		vm.callValue(value, argCount)
+		return
+	}
+
+	vm.invokeFromClass(instance.Class, name, argCount)
+}
+
+// member returns the member called name of a value that is not an
+// instance but still has some.
+func (vm *VM) member(value repr.Value, name string) repr.Value {
+	switch value.Type {
+	case repr.VAL_GENERATOR:
+		return vm.generatorMethod(value.AsGenerator(), name)
+	case repr.VAL_ENUM:
+		enum := value.AsEnum()
+		member, ok := enum.Member(name)
+		if !ok {
+			runtimeError(fmt.Sprintf("Enum '%s' has no member '%s'.", enum.Name, name))
+		}
+		return repr.EnumMemberVal(member)
+	case repr.VAL_ENUM_MEMBER:
+		return vm.enumMemberProperty(value.AsEnumMember(), name)
+	default:
+		return vm.moduleMember(value.AsModule(), name)
+	}
+}
+
+// hasMembers reports whether the properties of value are looked up by
+// member rather than read from an instance's fields and class.
+func (vm *VM) hasMembers(value repr.Value) bool {
+	switch value.Type {
+	case repr.VAL_MODULE, repr.VAL_GENERATOR, repr.VAL_ENUM, repr.VAL_ENUM_MEMBER:
+		return true
+	default:
 		return false
 	}
 }
 
-/*
-func (vm *VM) line() int {
-	instruction := vm.Chunk.Code[vm.IP]
-	line := vm.Chunk.Code[instruction]
+func (vm *VM) enumMemberProperty(member *repr.EnumMember, name string) repr.Value {
+	switch name {
+	case "name":
+		return repr.StringVal(member.Name)
+	case "ordinal":
+		return repr.IntVal(int64(member.Ordinal))
+	default:
+		runtimeError(fmt.Sprintf("Undefined property '%s'.", name))
+		return repr.NilVal()
+	}
+}
+
+func (vm *VM) bindMethod(class *repr.Class, name string) {
+	method, ok := class.Methods[name]
+	if !ok {
+		runtimeError(fmt.Sprintf("Undefined property '%s'.", name))
+	}
+
+	bound := repr.NewBoundMethod(vm.peek(0), method)
+	vm.pop()
+	vm.push(repr.BoundMethodVal(bound))
+}
+
+// captureUpvalue returns the open upvalue for the given stack slot, creating
+// it if no closure has captured that slot yet.
+func (vm *VM) captureUpvalue(slot int) *repr.Upvalue {
+	for _, upvalue := range vm.Fiber.OpenUpvalues {
+		if upvalue.Slot == slot {
+			return upvalue
+		}
+	}
+
+	upvalue := repr.NewUpvalue(&vm.Fiber.Stack, slot)
+	vm.Fiber.OpenUpvalues = append(vm.Fiber.OpenUpvalues, upvalue)
+	return upvalue
+}
+
+// closeUpvalues closes every open upvalue pointing at or above the given
+// stack slot.
+func (vm *VM) closeUpvalues(last int) {
+	stillOpen := vm.Fiber.OpenUpvalues[:0]
+	for _, upvalue := range vm.Fiber.OpenUpvalues {
+		if upvalue.Slot >= last {
+			upvalue.Close()
+		} else {
+			stillOpen = append(stillOpen, upvalue)
+		}
+	}
+	vm.Fiber.OpenUpvalues = stillOpen
 }
-*/
 
 func (vm *VM) binaryOp(op byte) {
-	var a, b float64
 	byteb, bytea := vm.peek(0), vm.peek(1)
 	if op == repr.OP_ADD && bytea.IsString() && byteb.IsString() {
 		vm.concatenate()
 		return
-	} else if bytea.IsNumber() && byteb.IsNumber() {
-		b, a = vm.pop().AsNumber(), vm.pop().AsNumber()
+	} else if !bytea.IsNumeric() || !byteb.IsNumeric() {
+		runtimeError("Operands must be numbers.")
+	}
+
+	b, a := vm.pop(), vm.pop()
+	if a.IsInt() && b.IsInt() {
+		vm.push(vm.intOp(op, a.AsInt(), b.AsInt()))
 	} else {
-		loxerror.Error(-1, fmt.Sprintf("Operands must be a number [%v %d %v]\n", bytea, op, byteb))
+		// Mixing an integer with a float promotes the integer.
+		vm.push(vm.floatOp(op, a.ToFloat(), b.ToFloat()))
 	}
+}
 
+func (vm *VM) floatOp(op byte, a, b float64) repr.Value {
 	switch op {
 	case repr.OP_GREATER:
-		vm.push(repr.BoolVal(a > b))
+		return repr.BoolVal(a > b)
 	case repr.OP_LESS:
-		vm.push(repr.BoolVal(a < b))
+		return repr.BoolVal(a < b)
 	case repr.OP_ADD:
-		vm.push(repr.NumberVal(a + b))
+		return repr.NumberVal(a + b)
 	case repr.OP_SUBTRACT:
-		vm.push(repr.NumberVal(a - b))
+		return repr.NumberVal(a - b)
 	case repr.OP_MULTIPLY:
-		vm.push(repr.NumberVal(a * b))
+		return repr.NumberVal(a * b)
 	case repr.OP_DIVIDE:
-		vm.push(repr.NumberVal(a / b))
+		return repr.NumberVal(a / b)
+	case repr.OP_FLOOR_DIVIDE:
+		if b == 0 {
+			runtimeError("Division by zero.")
+		}
+		return repr.NumberVal(math.Floor(a / b))
+	case repr.OP_MODULO:
+		// The result takes the sign of the divisor, so that
+		// a == b * (a // b) + a % b.
+		if b == 0 {
+			runtimeError("Division by zero.")
+		}
+		remainder := math.Mod(a, b)
+		if remainder != 0 && (remainder < 0) != (b < 0) {
+			remainder += b
+		}
+		return repr.NumberVal(remainder)
+	case repr.OP_POWER:
+		return repr.NumberVal(math.Pow(a, b))
+	default:
+		// Unreachable
+		return repr.NilVal()
+	}
+}
+
+// integer converts an operand of a bitwise operator to an int64. Only
+// integers and floats holding an exact integer are accepted.
+func (vm *VM) integer(value repr.Value) int64 {
+	i, ok := value.ToInt()
+	if !ok {
+		runtimeError("Operands must be integers.")
+	}
+	return i
+}
+
+func (vm *VM) bitwiseOp(op byte) {
+	b, a := vm.integer(vm.pop()), vm.integer(vm.pop())
+
+	var result int64
+	switch op {
+	case repr.OP_BIT_AND:
+		result = a & b
+	case repr.OP_BIT_OR:
+		result = a | b
+	case repr.OP_BIT_XOR:
+		result = a ^ b
+	case repr.OP_SHIFT_LEFT, repr.OP_SHIFT_RIGHT:
+		if b < 0 {
+			runtimeError("Shift count must not be negative.")
+		}
+		if op == repr.OP_SHIFT_LEFT {
+			result = a << uint64(b)
+		} else {
+			result = a >> uint64(b)
+		}
+	}
+	vm.push(repr.IntVal(result))
+}
+
+// index converts a Lox index into a Go index for a sequence of the given
+// length. Negative indices count from the end of the sequence.
+func (vm *VM) index(index repr.Value, length int) int {
+	i64, ok := index.ToInt()
+	if !ok {
+		runtimeError("Index must be an integer.")
+	}
+
+	if i64 < 0 {
+		i64 += int64(length)
+	}
+	if i64 < 0 || i64 >= int64(length) {
+		runtimeError("Index out of range.")
+	}
+	return int(i64)
+}
+
+// sliceBounds converts optional Lox slice bounds into Go slice bounds,
+// clamping them to the sequence.
+func (vm *VM) sliceBounds(start, end repr.Value, length int) (int, int) {
+	bound := func(value repr.Value, missing int) int {
+		if value.IsNil() {
+			return missing
+		}
+		i, ok := value.ToInt()
+		if !ok {
+			runtimeError("Slice bounds must be integers.")
+		}
+
+		if i < 0 {
+			i += int64(length)
+		}
+		if i < 0 {
+			return 0
+		} else if i > int64(length) {
+			return length
+		}
+		return int(i)
+	}
+
+	low, high := bound(start, 0), bound(end, length)
+	if high < low {
+		high = low
+	}
+	return low, high
+}
+
+func (vm *VM) mapKey(key repr.Value) repr.Value {
+	if !key.IsHashable() {
+		runtimeError("Map keys must be strings, numbers, booleans, enum members or nil.")
+	}
+	return key
+}
+
+func (vm *VM) indexGet() {
+	index, target := vm.pop(), vm.pop()
+	switch target.Type {
+	case repr.VAL_MAP:
+		value, ok := target.AsMap().Get(vm.mapKey(index))
+		if !ok {
+			runtimeError(fmt.Sprintf("Undefined key '%s'.", index))
+		}
+		vm.push(value)
+	case repr.VAL_LIST:
+		items := target.AsList().Items
+		vm.push(items[vm.index(index, len(items))])
+	case repr.VAL_TUPLE:
+		items := target.AsTuple().Items
+		vm.push(items[vm.index(index, len(items))])
+	case repr.VAL_ENUM:
+		members := target.AsEnum().Members
+		vm.push(repr.EnumMemberVal(members[vm.index(index, len(members))]))
+	case repr.VAL_STRING:
+		chars := []rune(target.AsString())
+		vm.push(repr.StringVal(string(chars[vm.index(index, len(chars))])))
+	default:
+		runtimeError("Can only index lists, tuples, maps, strings and enums.")
+	}
+}
+
+func (vm *VM) indexSet() {
+	value, index, target := vm.pop(), vm.pop(), vm.pop()
+	switch target.Type {
+	case repr.VAL_LIST:
+		items := target.AsList().Items
+		items[vm.index(index, len(items))] = value
+	case repr.VAL_MAP:
+		target.AsMap().Set(vm.mapKey(index), value)
+	case repr.VAL_TUPLE:
+		runtimeError("Tuples are immutable.")
+	default:
+		runtimeError("Can only assign to list elements and map entries.")
+	}
+	vm.push(value)
+}
+
+func (vm *VM) slice() {
+	end, start, target := vm.pop(), vm.pop(), vm.pop()
+	switch target.Type {
+	case repr.VAL_LIST:
+		items := target.AsList().Items
+		low, high := vm.sliceBounds(start, end, len(items))
+		sliced := make([]repr.Value, high-low)
+		copy(sliced, items[low:high])
+		vm.push(repr.ListVal(repr.NewList(sliced)))
+	case repr.VAL_STRING:
+		chars := []rune(target.AsString())
+		low, high := vm.sliceBounds(start, end, len(chars))
+		vm.push(repr.StringVal(string(chars[low:high])))
+	default:
+		runtimeError("Can only slice lists and strings.")
+	}
+}
+
+// unpack replaces the tuple or list on top of the stack with its items,
+// checking that it holds exactly count of them.
+func (vm *VM) unpack(count int) {
+	var items []repr.Value
+	switch value := vm.pop(); value.Type {
+	case repr.VAL_TUPLE:
+		items = value.AsTuple().Items
+	case repr.VAL_LIST:
+		items = value.AsList().Items
+	default:
+		runtimeError("Can only unpack tuples and lists.")
+	}
+
+	if len(items) != count {
+		runtimeError(fmt.Sprintf("Expected %d values to unpack but got %d.", count, len(items)))
+	}
+	for _, item := range items {
+		vm.push(item)
 	}
 }
 
@@ -154,27 +674,30 @@ func (vm *VM) isFalsey(v repr.Value) bool {
 
 func (vm *VM) readByte() byte {
 	frame := vm.CurrFrame()
-	byteRead := frame.Function.Chunk.Code[frame.IP]
+	byteRead := frame.Closure.Function.Chunk.Code[frame.IP]
 	frame.IP++
 	return byteRead
 }
 
 func (vm *VM) readConstant() repr.Value {
 	byteRead := vm.readByte()
-	return vm.CurrFrame().Function.Chunk.Constants[byteRead]
+	return vm.CurrFrame().Closure.Function.Chunk.Constants[byteRead]
 }
 
 func (vm *VM) readShort() int {
 	frame := vm.CurrFrame()
 	frame.IP += 2
-	short := int(frame.Function.Chunk.Code[frame.IP-2])<<8 | int(frame.Function.Chunk.Code[frame.IP-1])
+	code := frame.Closure.Function.Chunk.Code
+	short := int(code[frame.IP-2])<<8 | int(code[frame.IP-1])
 	return short
 }
 
-func (vm *VM) run() InterpretResult {
+// execute runs instructions until the frame at baseFrame returns. Exceptions
+// escape it as panics; see run.
+func (vm *VM) execute(baseFrame int) InterpretResult {
 	for {
 		instruction := vm.readByte()
-		//fmt.Printf("%d: Stack: %v\n", instruction, vm.Stack)
+		//fmt.Printf("%d: Stack: %v\n", instruction, vm.Fiber.Stack)
 		switch instruction {
 		case repr.OP_CONSTANT:
 			constant := vm.readConstant()
@@ -189,42 +712,137 @@ func (vm *VM) run() InterpretResult {
 			vm.pop()
 		case repr.OP_GET_LOCAL:
 			slot := int(vm.readByte())
-			vm.push(vm.Stack[slot+vm.CurrFrame().StackStart])
+			vm.push(vm.Fiber.Stack[slot+vm.CurrFrame().StackStart])
 		case repr.OP_SET_LOCAL:
 			slot := int(vm.readByte())
-			vm.Stack[slot+vm.CurrFrame().StackStart] = vm.peek(0)
+			vm.Fiber.Stack[slot+vm.CurrFrame().StackStart] = vm.peek(0)
 		case repr.OP_GET_GLOBAL:
 			name := vm.readConstant().AsString()
-			val, ok := vm.Globals[name]
+			val, ok := vm.CurrFrame().Closure.Module.Globals[name]
+			if !ok {
+				val, ok = vm.Builtins[name]
+			}
 			if !ok {
-				loxerror.Error(-1, fmt.Sprintf("Undefined variable '%s'.", name))
-				return INTERPRET_RUNTIME_ERROR
+				runtimeError(fmt.Sprintf("Undefined variable '%s'.", name))
 			}
 			vm.push(val)
 		case repr.OP_DEFINE_GLOBAL:
 			name := vm.readConstant().AsString()
-			vm.Globals[name] = vm.pop()
+			module := vm.CurrFrame().Closure.Module
+			if module.Constants[name] {
+				runtimeError(fmt.Sprintf("Cannot redeclare constant '%s'.", name))
+			}
+			module.Globals[name] = vm.pop()
+		case repr.OP_DEFINE_CONST:
+			name := vm.readConstant().AsString()
+			module := vm.CurrFrame().Closure.Module
+			if module.Constants[name] {
+				runtimeError(fmt.Sprintf("Cannot redeclare constant '%s'.", name))
+			}
+			module.Globals[name] = vm.pop()
+			module.Constants[name] = true
 		case repr.OP_SET_GLOBAL:
 			name := vm.readConstant().AsString()
-			_, ok := vm.Globals[name]
+			module := vm.CurrFrame().Closure.Module
+			_, ok := module.Globals[name]
 			if !ok {
-				loxerror.Error(-1, fmt.Sprintf("Undefined variable '%s'.", name))
+				runtimeError(fmt.Sprintf("Undefined variable '%s'.", name))
+			}
+			if module.Constants[name] {
+				runtimeError(fmt.Sprintf("Cannot assign to constant '%s'.", name))
+			}
+			module.Globals[name] = vm.peek(0)
+		case repr.OP_GET_UPVALUE:
+			slot := vm.readByte()
+			vm.push(vm.CurrFrame().Closure.Upvalues[slot].Get())
+		case repr.OP_SET_UPVALUE:
+			slot := vm.readByte()
+			vm.CurrFrame().Closure.Upvalues[slot].Set(vm.peek(0))
+		case repr.OP_GET_PROPERTY:
+			if vm.hasMembers(vm.peek(0)) {
+				vm.push(vm.member(vm.pop(), vm.readConstant().AsString()))
+				continue
+			}
+			if !vm.peek(0).IsInstance() {
+				runtimeError("Only instances have properties.")
+			}
+
+			instance := vm.peek(0).AsInstance()
+			name := vm.readConstant().AsString()
+			if value, ok := instance.Fields[name]; ok {
+				vm.pop()
+				vm.push(value)
+			} else {
+				vm.bindMethod(instance.Class, name)
+			}
+		case repr.OP_SET_PROPERTY:
+			if !vm.peek(1).IsInstance() {
+				runtimeError("Only instances have fields.")
+			}
+
+			instance := vm.peek(1).AsInstance()
+			instance.Fields[vm.readConstant().AsString()] = vm.peek(0)
+			value := vm.pop()
+			vm.pop()
+			vm.push(value)
+		case repr.OP_GET_SUPER:
+			name := vm.readConstant().AsString()
+			superclass := vm.pop().AsClass()
+			vm.bindMethod(superclass, name)
+		case repr.OP_BUILD_LIST:
+			itemCount := int(vm.readByte())
+			items := make([]repr.Value, itemCount)
+			copy(items, vm.Fiber.Stack[len(vm.Fiber.Stack)-itemCount:])
+			vm.Fiber.Stack = vm.Fiber.Stack[:len(vm.Fiber.Stack)-itemCount]
+			vm.push(repr.ListVal(repr.NewList(items)))
+		case repr.OP_BUILD_MAP:
+			entryCount := int(vm.readByte())
+			entries := vm.Fiber.Stack[len(vm.Fiber.Stack)-2*entryCount:]
+			m := repr.NewMap()
+			for i := 0; i < len(entries); i += 2 {
+				m.Set(vm.mapKey(entries[i]), entries[i+1])
 			}
-			vm.Globals[name] = vm.peek(0)
+			vm.Fiber.Stack = vm.Fiber.Stack[:len(vm.Fiber.Stack)-2*entryCount]
+			vm.push(repr.MapVal(m))
+		case repr.OP_BUILD_TUPLE:
+			itemCount := int(vm.readByte())
+			items := make([]repr.Value, itemCount)
+			copy(items, vm.Fiber.Stack[len(vm.Fiber.Stack)-itemCount:])
+			vm.Fiber.Stack = vm.Fiber.Stack[:len(vm.Fiber.Stack)-itemCount]
+			vm.push(repr.TupleVal(repr.NewTuple(items)))
+		case repr.OP_INDEX_GET:
+			vm.indexGet()
+		case repr.OP_INDEX_SET:
+			vm.indexSet()
+		case repr.OP_SLICE:
+			vm.slice()
+		case repr.OP_UNPACK:
+			vm.unpack(int(vm.readByte()))
 		case repr.OP_EQUAL:
 			b, a := vm.pop(), vm.pop()
 			vm.push(repr.BoolVal(a.Equals(b)))
 		case repr.OP_GREATER, repr.OP_LESS,
-			repr.OP_ADD, repr.OP_SUBTRACT, repr.OP_MULTIPLY, repr.OP_DIVIDE:
+			repr.OP_ADD, repr.OP_SUBTRACT, repr.OP_MULTIPLY, repr.OP_DIVIDE,
+			repr.OP_FLOOR_DIVIDE, repr.OP_MODULO, repr.OP_POWER:
 			vm.binaryOp(instruction)
 		case repr.OP_NOT:
 			vm.push(repr.BoolVal(vm.isFalsey(vm.pop())))
 		case repr.OP_NEGATE:
-			if !vm.peek(0).IsNumber() {
-				loxerror.Error(-1, "Operand must be a number.")
-				return INTERPRET_RUNTIME_ERROR
+			if !vm.peek(0).IsNumeric() {
+				runtimeError("Operand must be a number.")
+			}
+			if operand := vm.pop(); operand.IsInt() {
+				vm.push(vm.intOp(repr.OP_SUBTRACT, 0, operand.AsInt()))
+			} else {
+				vm.push(repr.NumberVal(-operand.AsNumber()))
 			}
-			vm.push(repr.NumberVal(-vm.pop().AsNumber()))
+		case repr.OP_BIT_AND, repr.OP_BIT_OR, repr.OP_BIT_XOR,
+			repr.OP_SHIFT_LEFT, repr.OP_SHIFT_RIGHT:
+			vm.bitwiseOp(instruction)
+		case repr.OP_BIT_NOT:
+			vm.push(repr.IntVal(^vm.integer(vm.pop())))
+		case repr.OP_TO_STRING:
+			vm.push(repr.StringVal(vm.pop().String()))
 		case repr.OP_PRINT:
 			printVal := vm.pop()
 			fmt.Println(printVal.String())
@@ -232,26 +850,127 @@ func (vm *VM) run() InterpretResult {
 		case repr.OP_JUMP:
 			offset := vm.readShort()
 			vm.CurrFrame().IP += offset
+		case repr.OP_GET_ITER:
+			vm.push(vm.iterate(vm.pop()))
+		case repr.OP_FOR_ITER:
+			offset := vm.readShort()
+			if value, ok := vm.peek(0).AsIterator().Next(); ok {
+				vm.push(value)
+			} else {
+				vm.CurrFrame().IP += offset
+			}
 		case repr.OP_JUMP_IF_FALSE:
 			offset := vm.readShort()
 			if vm.isFalsey(vm.peek(0)) {
 				vm.CurrFrame().IP += offset
 			}
+		case repr.OP_JUMP_IF_PRESENT:
+			slot := int(vm.readByte())
+			offset := vm.readShort()
+			if !vm.Fiber.Stack[slot+vm.CurrFrame().StackStart].IsAbsent() {
+				vm.CurrFrame().IP += offset
+			}
 		case repr.OP_LOOP:
 			offset := vm.readShort()
 			vm.CurrFrame().IP -= offset
 		case repr.OP_CALL:
 			argCount := int(vm.readByte())
-			if !vm.callValue(vm.peek(argCount), argCount) {
-				return INTERPRET_RUNTIME_ERROR
+			vm.callValue(vm.peek(argCount), argCount)
+		case repr.OP_SPAWN:
+			vm.spawn(int(vm.readByte()))
+		case repr.OP_CALL_NAMED:
+			argCount := int(vm.readByte())
+			names := vm.readConstant().AsList().Items
+			vm.callNamed(vm.peek(argCount), argCount, names)
+		case repr.OP_CALL_SPREAD:
+			argCount := vm.spreadArgs(int(vm.readByte()), vm.readConstant().AsList().Items)
+			vm.callValue(vm.peek(argCount), argCount)
+		case repr.OP_INVOKE:
+			method := vm.readConstant().AsString()
+			argCount := int(vm.readByte())
+			vm.invoke(method, argCount)
+		case repr.OP_SUPER_INVOKE:
+			method := vm.readConstant().AsString()
+			argCount := int(vm.readByte())
+			superclass := vm.pop().AsClass()
+			vm.invokeFromClass(superclass, method, argCount)
+		case repr.OP_CLOSURE:
+			function := vm.readConstant().AsFunction()
+			closure := repr.NewClosure(function, vm.CurrFrame().Closure.Module)
+			vm.push(repr.ClosureVal(closure))
+			for i := range closure.Upvalues {
+				isLocal := vm.readByte()
+				index := int(vm.readByte())
+				if isLocal == 1 {
+					closure.Upvalues[i] = vm.captureUpvalue(vm.CurrFrame().StackStart + index)
+				} else {
+					closure.Upvalues[i] = vm.CurrFrame().Closure.Upvalues[index]
+				}
 			}
+		case repr.OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(len(vm.Fiber.Stack) - 1)
+			vm.pop()
 		case repr.OP_RETURN:
 			result := vm.pop()
+			vm.closeUpvalues(vm.CurrFrame().StackStart)
 			vm.RemoveFrame()
 			if vm.FrameCount() == 0 {
+				if vm.Fiber != vm.main && vm.Fiber.Generator == nil {
+					// A spawned fiber is done, so another one takes over.
+					vm.schedule()
+					continue
+				}
+				if vm.Fiber.Generator != nil {
+					// Left for resume to take as the generator's result.
+					vm.push(result)
+				}
 				return INTERPRET_OK
 			}
 			vm.push(result)
+			if vm.FrameCount() == baseFrame {
+				return INTERPRET_OK
+			}
+		case repr.OP_THROW:
+			vm.throw(vm.pop())
+		case repr.OP_ASSERT:
+			failure := vm.readConstant().AsString()
+			if message := vm.pop(); !message.IsNil() {
+				runtimeError(failure + ": " + message.String())
+			}
+			runtimeError(failure + ".")
+		case repr.OP_YIELD:
+			// The fiber is left suspended with the value yielded on top of
+			// its stack, for resume to take.
+			return INTERPRET_OK
+		case repr.OP_CLASS:
+			vm.push(repr.ClassVal(repr.NewClass(vm.readConstant().AsString())))
+		case repr.OP_INHERIT:
+			superclass := vm.peek(1)
+			if !superclass.IsClass() {
+				runtimeError("Superclass must be a class.")
+			}
+
+			subclass := vm.peek(0).AsClass()
+			for name, method := range superclass.AsClass().Methods {
+				subclass.Methods[name] = method
+			}
+			vm.pop()
+		case repr.OP_IMPORT:
+			vm.importModule(vm.readConstant().AsString())
+		case repr.OP_METHOD:
+			method := vm.peek(0).AsClosure()
+			class := vm.peek(1).AsClass()
+			class.Methods[vm.readConstant().AsString()] = method
+			vm.pop()
+		case repr.OP_ENUM:
+			name := vm.readConstant().AsString()
+			memberCount := int(vm.readByte())
+			names := make([]string, memberCount)
+			for i, value := range vm.Fiber.Stack[len(vm.Fiber.Stack)-memberCount:] {
+				names[i] = value.AsString()
+			}
+			vm.Fiber.Stack = vm.Fiber.Stack[:len(vm.Fiber.Stack)-memberCount]
+			vm.push(repr.EnumVal(repr.NewEnum(name, names)))
 		}
 	}
 }